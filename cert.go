@@ -0,0 +1,133 @@
+package rest
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ClientAuth - policy for tls client certificates
+type ClientAuth int
+
+const (
+	ClientAuthNone             ClientAuth = iota // client certificate is not requested
+	ClientAuthRequest                            // client certificate is requested and verified if provided
+	ClientAuthRequireAndVerify                   // valid client certificate is required
+)
+
+// tlsClientAuth - returns tls client auth type and pool of client CAs for the config
+func (c *SSLConfig) tlsClientAuth() (tls.ClientAuthType, *x509.CertPool, error) {
+	var authType tls.ClientAuthType
+	switch c.ClientAuth {
+	case ClientAuthNone:
+		return tls.NoClientCert, nil, nil
+	case ClientAuthRequest:
+		authType = tls.VerifyClientCertIfGiven
+	case ClientAuthRequireAndVerify:
+		authType = tls.RequireAndVerifyClientCert
+	default:
+		return tls.NoClientCert, nil, fmt.Errorf("unknown client auth mode: %d", c.ClientAuth)
+	}
+
+	if c.ClientCAPath == "" {
+		return tls.NoClientCert, nil, fmt.Errorf("client CA bundle is required for client auth")
+	}
+	b, err := os.ReadFile(c.ClientCAPath)
+	if err != nil {
+		return tls.NoClientCert, nil, fmt.Errorf("read client CA bundle, %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return tls.NoClientCert, nil, fmt.Errorf("no certificates in client CA bundle: %s", c.ClientCAPath)
+	}
+
+	return authType, pool, nil
+}
+
+// certReloader - serves tls certificate from files and reloads it when the files are changed
+type certReloader struct {
+	certPath string
+	keyPath  string
+
+	cert atomic.Pointer[tls.Certificate]
+
+	mu      sync.Mutex
+	certMod time.Time
+	keyMod  time.Time
+}
+
+// newCertReloader - loads the keypair and returns reloader for it
+func newCertReloader(certPath, keyPath string) (*certReloader, error) {
+	c := &certReloader{
+		certPath: certPath,
+		keyPath:  keyPath,
+	}
+	if _, err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// GetCertificate - callback for tls.Config, returns current certificate
+func (c *certReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return c.cert.Load(), nil
+}
+
+// reload - loads the keypair if modification time of the certificate or the key was changed.
+// Current certificate is kept if the new pair cannot be loaded.
+func (c *certReloader) reload() (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	certInfo, err := os.Stat(c.certPath)
+	if err != nil {
+		return false, fmt.Errorf("stat ssl certificate, %w", err)
+	}
+	keyInfo, err := os.Stat(c.keyPath)
+	if err != nil {
+		return false, fmt.Errorf("stat ssl key, %w", err)
+	}
+
+	if certInfo.ModTime().Equal(c.certMod) && keyInfo.ModTime().Equal(c.keyMod) {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certPath, c.keyPath)
+	if err != nil {
+		return false, fmt.Errorf("load ssl keypair, %w", err)
+	}
+
+	c.cert.Store(&cert)
+	c.certMod = certInfo.ModTime()
+	c.keyMod = keyInfo.ModTime()
+
+	return true, nil
+}
+
+// watch - polls the keypair files every interval until ctx is done
+func (c *certReloader) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := c.reload()
+			if err != nil {
+				log.Printf("[WARN] ssl certificate not reloaded, keep the current one, %s", err)
+				continue
+			}
+			if reloaded {
+				log.Printf("[INFO] ssl certificate reloaded from %s", c.certPath)
+			}
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package rest
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCertReloader(t *testing.T) {
+	t.Run("no files", func(t *testing.T) {
+		_, err := newCertReloader("./cert.pem", "./key.pem")
+		require.Error(t, err)
+	})
+
+	t.Run("reload on change", func(t *testing.T) {
+		dir := t.TempDir()
+		certPath, keyPath := generateCert(t, dir, "server")
+
+		c, err := newCertReloader(certPath, keyPath)
+		require.NoError(t, err)
+		require.Equal(t, "server", leafCommonName(t, c))
+
+		reloaded, err := c.reload()
+		require.NoError(t, err)
+		require.False(t, reloaded)
+
+		newCertPath, newKeyPath := generateCert(t, t.TempDir(), "rotated")
+		replaceFile(t, newCertPath, certPath)
+		replaceFile(t, newKeyPath, keyPath)
+
+		reloaded, err = c.reload()
+		require.NoError(t, err)
+		require.True(t, reloaded)
+		require.Equal(t, "rotated", leafCommonName(t, c))
+	})
+
+	t.Run("keep current certificate on broken pair", func(t *testing.T) {
+		dir := t.TempDir()
+		certPath, keyPath := generateCert(t, dir, "server")
+
+		c, err := newCertReloader(certPath, keyPath)
+		require.NoError(t, err)
+
+		_, otherKeyPath := generateCert(t, t.TempDir(), "other")
+		replaceFile(t, otherKeyPath, keyPath)
+
+		reloaded, err := c.reload()
+		require.Error(t, err)
+		require.False(t, reloaded)
+		require.Equal(t, "server", leafCommonName(t, c))
+	})
+
+	t.Run("watch", func(t *testing.T) {
+		certPath, keyPath := generateCert(t, t.TempDir(), "server")
+
+		srv := &Server{
+			Port: freePort(t),
+			SSL: &SSLConfig{
+				Port:           freePort(t),
+				CertPath:       certPath,
+				KeyPath:        keyPath,
+				ReloadInterval: 10 * time.Millisecond,
+			},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error)
+		go func() {
+			done <- srv.RunContext(ctx, nil)
+		}()
+		addr := fmt.Sprintf("localhost:%d", srv.SSL.Port)
+		waitForServer(t, addr)
+		require.Equal(t, "server", peerCommonName(t, addr))
+
+		newCertPath, newKeyPath := generateCert(t, t.TempDir(), "rotated")
+		replaceFile(t, newCertPath, certPath)
+		replaceFile(t, newKeyPath, keyPath)
+
+		for i := 0; i < 100 && peerCommonName(t, addr) != "rotated"; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		require.Equal(t, "rotated", peerCommonName(t, addr))
+
+		cancel()
+		require.NoError(t, <-done)
+	})
+}
+
+// replaceFile - copies src over dst and moves dst modification time forward
+func replaceFile(t *testing.T, src, dst string) {
+	t.Helper()
+
+	b, err := os.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dst, b, 0o600))
+
+	mod := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(dst, mod, mod))
+}
+
+func leafCommonName(t *testing.T, c *certReloader) string {
+	t.Helper()
+
+	cert, err := c.GetCertificate(nil)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+
+	return leaf.Subject.CommonName
+}
+
+func peerCommonName(t *testing.T, addr string) string {
+	t.Helper()
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+}
+
+func TestSSLConfig_tlsClientAuth(t *testing.T) {
+	caPath, _ := generateCert(t, t.TempDir(), "ca")
+
+	t.Run("none", func(t *testing.T) {
+		authType, pool, err := (&SSLConfig{}).tlsClientAuth()
+		require.NoError(t, err)
+		require.Equal(t, tls.NoClientCert, authType)
+		require.Nil(t, pool)
+	})
+	t.Run("request", func(t *testing.T) {
+		authType, pool, err := (&SSLConfig{ClientAuth: ClientAuthRequest, ClientCAPath: caPath}).tlsClientAuth()
+		require.NoError(t, err)
+		require.Equal(t, tls.VerifyClientCertIfGiven, authType)
+		require.NotNil(t, pool)
+	})
+	t.Run("require and verify", func(t *testing.T) {
+		authType, pool, err := (&SSLConfig{ClientAuth: ClientAuthRequireAndVerify, ClientCAPath: caPath}).tlsClientAuth()
+		require.NoError(t, err)
+		require.Equal(t, tls.RequireAndVerifyClientCert, authType)
+		require.NotNil(t, pool)
+	})
+	t.Run("no CA bundle", func(t *testing.T) {
+		_, _, err := (&SSLConfig{ClientAuth: ClientAuthRequireAndVerify}).tlsClientAuth()
+		require.Error(t, err)
+	})
+	t.Run("empty CA bundle", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "ca.pem")
+		require.NoError(t, os.WriteFile(path, []byte("nothing"), 0o600))
+
+		_, _, err := (&SSLConfig{ClientAuth: ClientAuthRequest, ClientCAPath: path}).tlsClientAuth()
+		require.Error(t, err)
+	})
+	t.Run("unknown mode", func(t *testing.T) {
+		_, _, err := (&SSLConfig{ClientAuth: 42, ClientCAPath: caPath}).tlsClientAuth()
+		require.Error(t, err)
+	})
+}
+
+func TestServer_RunContext_mTLS(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := generateCert(t, dir, "server")
+	clientCertPath, clientKeyPath := generateCert(t, dir, "client")
+
+	srv := &Server{
+		Port: freePort(t),
+		SSL: &SSLConfig{
+			Port:         freePort(t),
+			CertPath:     certPath,
+			KeyPath:      keyPath,
+			ClientCAPath: clientCertPath,
+			ClientAuth:   ClientAuthRequireAndVerify,
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error)
+	go func() {
+		done <- srv.RunContext(ctx, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cert, ok := GetPeerCertificate(r)
+			if !ok {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(cert.CommonName))
+		}))
+	}()
+	url := fmt.Sprintf("https://localhost:%d", srv.SSL.Port)
+	waitForServer(t, fmt.Sprintf("localhost:%d", srv.SSL.Port))
+
+	t.Run("without client certificate", func(t *testing.T) {
+		_, err := insecureClient().Get(url)
+		require.Error(t, err)
+	})
+
+	t.Run("with client certificate", func(t *testing.T) {
+		clientCert, err := tls.LoadX509KeyPair(clientCertPath, clientKeyPath)
+		require.NoError(t, err)
+
+		client := &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					InsecureSkipVerify: true,
+					Certificates:       []tls.Certificate{clientCert},
+				},
+			},
+		}
+		resp, err := client.Get(url)
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, "client", string(body))
+	})
+
+	cancel()
+	require.NoError(t, <-done)
+}
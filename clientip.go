@@ -0,0 +1,128 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+var clientIPKey = &contextKey{"client-ip"}
+
+// ClientIPResolver - resolves the client address of requests passed through trusted proxies.
+// Proxy headers are ignored if the request does not come from a trusted proxy.
+type ClientIPResolver struct {
+	TrustedProxies []netip.Prefix
+}
+
+// NewClientIPResolver - creates resolver trusting proxies from the given CIDRs or single addresses
+func NewClientIPResolver(trustedProxies ...string) (*ClientIPResolver, error) {
+	c := &ClientIPResolver{}
+	for _, proxy := range trustedProxies {
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(proxy)
+			if addrErr != nil {
+				return nil, fmt.Errorf("parse trusted proxy %q, %w", proxy, err)
+			}
+			prefix = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+		}
+		c.TrustedProxies = append(c.TrustedProxies, prefix.Masked())
+	}
+	return c, nil
+}
+
+// Resolve - returns the client address. For requests from trusted proxies CF-Connecting-IP and X-Real-Ip are used first,
+// then Forwarded or X-Forwarded-For are walked from the right to the first untrusted address.
+// Returns invalid address if RemoteAddr can not be parsed.
+func (c *ClientIPResolver) Resolve(r *http.Request) netip.Addr {
+	peer, ok := parseIP(r.RemoteAddr)
+	if !ok || !c.trusted(peer) {
+		return peer
+	}
+
+	for _, header := range []string{"CF-Connecting-IP", "X-Real-Ip"} {
+		if addr, ok := parseIP(r.Header.Get(header)); ok {
+			return addr
+		}
+	}
+
+	chain := forwardedFor(r.Header.Values("Forwarded"))
+	if len(chain) == 0 {
+		for _, v := range r.Header.Values("X-Forwarded-For") {
+			chain = append(chain, strings.Split(v, ",")...)
+		}
+	}
+
+	addr := peer
+	for i := len(chain) - 1; i >= 0; i-- {
+		hop, ok := parseIP(chain[i])
+		if !ok {
+			break
+		}
+		addr = hop
+		if !c.trusted(hop) {
+			break
+		}
+	}
+	return addr
+}
+
+// Middleware - stores the resolved client address in the request context, used by GetAddr and GetClientIP
+func (c *ClientIPResolver) Middleware(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if addr := c.Resolve(r); addr.IsValid() {
+			r = r.WithContext(context.WithValue(r.Context(), clientIPKey, addr))
+		}
+		next.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
+
+// GetClientIP - returns the client address stored by ClientIPResolver middleware
+func GetClientIP(ctx context.Context) (netip.Addr, bool) {
+	addr, ok := ctx.Value(clientIPKey).(netip.Addr)
+	return addr, ok
+}
+
+func (c *ClientIPResolver) trusted(addr netip.Addr) bool {
+	for _, prefix := range c.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedFor - returns for= values of the RFC 7239 Forwarded header elements
+func forwardedFor(values []string) []string {
+	var res []string
+	for _, v := range values {
+		for _, element := range strings.Split(v, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(key, "for") {
+					res = append(res, value)
+				}
+			}
+		}
+	}
+	return res
+}
+
+// parseIP - parses address with optional port, brackets or quotes
+func parseIP(s string) (netip.Addr, bool) {
+	s = strings.Trim(strings.TrimSpace(s), `"`)
+	if s == "" {
+		return netip.Addr{}, false
+	}
+	if addrPort, err := netip.ParseAddrPort(s); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
@@ -0,0 +1,154 @@
+package rest
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestNewClientIPResolver(t *testing.T) {
+	c, err := NewClientIPResolver("10.0.0.0/8", "192.168.1.1", "2001:db8::/32", "172.16.5.4/12")
+	require.NoError(t, err)
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.168.1.1/32"),
+		netip.MustParsePrefix("2001:db8::/32"),
+		netip.MustParsePrefix("172.16.0.0/12"),
+	}, c.TrustedProxies)
+
+	_, err = NewClientIPResolver("not-an-ip")
+	require.Error(t, err)
+}
+
+func TestClientIPResolver_Resolve(t *testing.T) {
+	c, err := NewClientIPResolver("10.0.0.0/8", "2001:db8::/32")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string][]string
+		want       string
+	}{
+		{name: "direct", remoteAddr: "203.0.113.1:1234", want: "203.0.113.1"},
+		{name: "direct ipv6", remoteAddr: "[2001:db9::1]:1234", want: "2001:db9::1"},
+		{name: "ipv4 mapped", remoteAddr: "[::ffff:203.0.113.1]:1234", want: "203.0.113.1"},
+		{name: "invalid remote addr", remoteAddr: "", want: "invalid IP"},
+		{
+			name:       "untrusted peer headers ignored",
+			remoteAddr: "203.0.113.1:1234",
+			headers: map[string][]string{
+				"Cf-Connecting-Ip": {"198.51.100.1"},
+				"X-Real-Ip":        {"198.51.100.2"},
+				"X-Forwarded-For":  {"198.51.100.3"},
+				"Forwarded":        {"for=198.51.100.4"},
+			},
+			want: "203.0.113.1",
+		},
+		{name: "cf connecting ip", remoteAddr: "10.0.0.1:1234", headers: map[string][]string{"Cf-Connecting-Ip": {"198.51.100.1"}, "X-Forwarded-For": {"198.51.100.3"}}, want: "198.51.100.1"},
+		{name: "x real ip", remoteAddr: "10.0.0.1:1234", headers: map[string][]string{"X-Real-Ip": {"198.51.100.2"}}, want: "198.51.100.2"},
+		{name: "x forwarded for", remoteAddr: "10.0.0.1:1234", headers: map[string][]string{"X-Forwarded-For": {"198.51.100.3"}}, want: "198.51.100.3"},
+		{
+			name:       "x forwarded for spoofed left entry",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.2.3.4, 198.51.100.3, 10.0.0.2"}},
+			want:       "198.51.100.3",
+		},
+		{
+			name:       "x forwarded for multiple headers",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.2.3.4", "198.51.100.3", "10.0.0.2"}},
+			want:       "198.51.100.3",
+		},
+		{
+			name:       "x forwarded for all trusted",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}},
+			want:       "10.0.0.3",
+		},
+		{
+			name:       "x forwarded for invalid entry",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.3, garbage, 10.0.0.2"}},
+			want:       "10.0.0.2",
+		},
+		{
+			name:       "forwarded",
+			remoteAddr: "10.0.0.1:1234",
+			headers: map[string][]string{
+				"Forwarded":       {`for=1.2.3.4, for="[2001:db9::1]:4711";proto=https, for=10.0.0.2;by=10.0.0.1`},
+				"X-Forwarded-For": {"198.51.100.3"},
+			},
+			want: "2001:db9::1",
+		},
+		{
+			name:       "forwarded unknown",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string][]string{"Forwarded": {"for=unknown, for=10.0.0.2"}},
+			want:       "10.0.0.2",
+		},
+		{
+			name:       "trusted ipv6 peer",
+			remoteAddr: "[2001:db8::1]:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.3:5678"}},
+			want:       "198.51.100.3",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for k, v := range tc.headers {
+				req.Header[k] = v
+			}
+			require.Equal(t, tc.want, c.Resolve(req).String())
+		})
+	}
+}
+
+func TestClientIPResolver_Middleware(t *testing.T) {
+	c, err := NewClientIPResolver("10.0.0.0/8")
+	require.NoError(t, err)
+
+	var addr string
+	var ip netip.Addr
+	var ok bool
+	handler := c.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		addr = GetAddr(r)
+		ip, ok = GetClientIP(r.Context())
+	}))
+
+	t.Run("resolved", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set("X-Forwarded-For", "1.2.3.4, 198.51.100.3")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		require.True(t, ok)
+		require.Equal(t, netip.MustParseAddr("198.51.100.3"), ip)
+		require.Equal(t, "198.51.100.3", addr)
+	})
+
+	t.Run("spoofed header", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "203.0.113.1:1234"
+		req.Header.Set("CF-Connecting-IP", "1.2.3.4")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		require.True(t, ok)
+		require.Equal(t, "203.0.113.1", addr)
+	})
+
+	t.Run("unresolved", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = ""
+		req.Header.Set("X-Real-Ip", "1.2.3.4")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		require.False(t, ok)
+		require.Equal(t, "1.2.3.4", addr)
+	})
+}
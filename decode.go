@@ -0,0 +1,245 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxBodySize - request body limit used by Decode, 1 MB
+const DefaultMaxBodySize = 1 << 20
+
+// DecodeOptions - settings of the request body decoding
+type DecodeOptions struct {
+	MaxBodySize           int64 // body size limit in bytes, DefaultMaxBodySize if zero, negative disables the limit
+	DisallowUnknownFields bool  // reject objects with fields missing in the target struct
+	AnyContentType        bool  // skip the Content-Type check, otherwise only application/json and */*+json are accepted
+}
+
+// BodyTooLargeError - request body exceeds DecodeOptions.MaxBodySize
+type BodyTooLargeError struct {
+	Limit int64
+}
+
+// MediaTypeError - request Content-Type is not JSON
+type MediaTypeError struct {
+	ContentType string
+}
+
+// SyntaxError - request body is not a single valid JSON value
+type SyntaxError struct {
+	Offset int64 // position in the body where the error occurred
+	Err    error
+}
+
+// TypeError - JSON value does not fit the type of the target field
+type TypeError struct {
+	Field  string // dotted path of the field, empty for the top level value
+	Value  string // JSON type of the value, like string or number
+	Type   string // expected Go type
+	Offset int64
+}
+
+// UnknownFieldError - object has a field missing in the target struct, returned with DisallowUnknownFields only
+type UnknownFieldError struct {
+	Field string
+}
+
+// Decode - decodes JSON request body to T with the default options
+func Decode[T any](r *http.Request) (T, error) {
+	return DecodeWith[T](r, DecodeOptions{})
+}
+
+// DecodeWith - decodes JSON request body to T and validates it by the validate tags. The body must contain exactly one JSON value.
+// All decoding errors match ErrUnmarshal with errors.Is and render with the proper status by ErrorResponse,
+// validation errors are returned as *ValidationError.
+func DecodeWith[T any](r *http.Request, opts DecodeOptions) (T, error) {
+	var v T
+	if r == nil || r.Body == nil {
+		return v, ErrEmptyRequest
+	}
+	if !opts.AnyContentType {
+		if err := checkContentType(r.Header.Get("Content-Type")); err != nil {
+			return v, err
+		}
+	}
+
+	limit := opts.MaxBodySize
+	if limit == 0 {
+		limit = DefaultMaxBodySize
+	}
+	body := r.Body
+	if limit > 0 {
+		body = http.MaxBytesReader(nil, r.Body, limit)
+	}
+	defer func() { _ = body.Close() }()
+
+	dec := json.NewDecoder(body)
+	if opts.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(&v); err != nil {
+		return v, decodeError(err, dec)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return v, &BodyTooLargeError{Limit: maxBytesErr.Limit}
+		}
+		return v, &SyntaxError{Offset: dec.InputOffset(), Err: errors.New("unexpected data after JSON value")}
+	}
+
+	return v, Validate(&v)
+}
+
+// checkContentType - accepts empty, application/json and structured syntax suffix +json media types
+func checkContentType(contentType string) error {
+	if contentType == "" {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+		return &MediaTypeError{ContentType: contentType}
+	}
+	return nil
+}
+
+// decodeError - converts errors of json.Decoder to the typed decoding errors
+func decodeError(err error, dec *json.Decoder) error {
+	var maxBytesErr *http.MaxBytesError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return &BodyTooLargeError{Limit: maxBytesErr.Limit}
+	case errors.Is(err, io.EOF):
+		return &SyntaxError{Err: errors.New("empty body")}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &SyntaxError{Offset: dec.InputOffset(), Err: errors.New("unexpected end of JSON input")}
+	case errors.As(err, &syntaxErr):
+		return &SyntaxError{Offset: syntaxErr.Offset, Err: err}
+	case errors.As(err, &typeErr):
+		return &TypeError{Field: typeErr.Field, Value: typeErr.Value, Type: typeErr.Type.String(), Offset: typeErr.Offset}
+	}
+
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		if unquoted, e := strconv.Unquote(field); e == nil {
+			field = unquoted
+		}
+		return &UnknownFieldError{Field: field}
+	}
+	return &SyntaxError{Offset: dec.InputOffset(), Err: err}
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("request body is larger than %d bytes", e.Limit)
+}
+
+func (e *BodyTooLargeError) Is(target error) bool {
+	return target == ErrUnmarshal
+}
+
+func (e *BodyTooLargeError) StatusCode() int {
+	return http.StatusRequestEntityTooLarge
+}
+
+func (e *BodyTooLargeError) PublicCode() string {
+	return "REQUEST_ENTITY_TOO_LARGE"
+}
+
+func (e *BodyTooLargeError) PublicMessage() string {
+	return e.Error()
+}
+
+func (e *MediaTypeError) Error() string {
+	return fmt.Sprintf("unsupported content type %q, expected application/json", e.ContentType)
+}
+
+func (e *MediaTypeError) Is(target error) bool {
+	return target == ErrUnmarshal
+}
+
+func (e *MediaTypeError) StatusCode() int {
+	return http.StatusUnsupportedMediaType
+}
+
+func (e *MediaTypeError) PublicCode() string {
+	return "UNSUPPORTED_MEDIA_TYPE"
+}
+
+func (e *MediaTypeError) PublicMessage() string {
+	return e.Error()
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid JSON at offset %d: %s", e.Offset, strings.TrimPrefix(e.Err.Error(), "json: "))
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+func (e *SyntaxError) Is(target error) bool {
+	return target == ErrUnmarshal
+}
+
+func (e *SyntaxError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+func (e *SyntaxError) PublicCode() string {
+	return ErrUnmarshal.Error()
+}
+
+func (e *SyntaxError) PublicMessage() string {
+	return e.Error()
+}
+
+func (e *TypeError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("cannot use JSON %s as %s", e.Value, e.Type)
+	}
+	return fmt.Sprintf("field %q: cannot use JSON %s as %s", e.Field, e.Value, e.Type)
+}
+
+func (e *TypeError) Is(target error) bool {
+	return target == ErrUnmarshal
+}
+
+func (e *TypeError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+func (e *TypeError) PublicCode() string {
+	return ErrUnmarshal.Error()
+}
+
+func (e *TypeError) PublicMessage() string {
+	return e.Error()
+}
+
+func (e *UnknownFieldError) Error() string {
+	return fmt.Sprintf("unknown field %q", e.Field)
+}
+
+func (e *UnknownFieldError) Is(target error) bool {
+	return target == ErrUnmarshal
+}
+
+func (e *UnknownFieldError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+func (e *UnknownFieldError) PublicCode() string {
+	return ErrUnmarshal.Error()
+}
+
+func (e *UnknownFieldError) PublicMessage() string {
+	return e.Error()
+}
@@ -0,0 +1,208 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type decodeTarget struct {
+	Name    string `json:"name"`
+	Age     int    `json:"age"`
+	Address struct {
+		Zip int `json:"zip"`
+	} `json:"address"`
+}
+
+func TestDecode(t *testing.T) {
+	newRequest := func(body, contentType string) *http.Request {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		return req
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		v, err := Decode[decodeTarget](newRequest(`{"name":"John","age":30,"address":{"zip":12345},"extra":true}`+"\n", "application/json; charset=utf-8"))
+		require.NoError(t, err)
+		require.Equal(t, "John", v.Name)
+		require.Equal(t, 30, v.Age)
+		require.Equal(t, 12345, v.Address.Zip)
+	})
+
+	t.Run("slice", func(t *testing.T) {
+		v, err := Decode[[]int](newRequest(`[1, 2, 3]`, ""))
+		require.NoError(t, err)
+		require.Equal(t, []int{1, 2, 3}, v)
+	})
+
+	t.Run("json suffix", func(t *testing.T) {
+		_, err := Decode[decodeTarget](newRequest(`{}`, "application/merge-patch+json"))
+		require.NoError(t, err)
+	})
+
+	t.Run("nil request", func(t *testing.T) {
+		_, err := Decode[decodeTarget](nil)
+		require.Equal(t, ErrEmptyRequest, err)
+	})
+
+	testCases := []struct {
+		name   string
+		body   string
+		ctype  string
+		opts   DecodeOptions
+		status int
+		check  func(t *testing.T, err error)
+	}{
+		{
+			name: "media type", body: `{}`, ctype: "text/plain", status: http.StatusUnsupportedMediaType,
+			check: func(t *testing.T, err error) {
+				var e *MediaTypeError
+				require.True(t, errors.As(err, &e))
+				require.Equal(t, "text/plain", e.ContentType)
+			},
+		},
+		{
+			name: "any media type", body: `{}`, ctype: "text/plain", opts: DecodeOptions{AnyContentType: true},
+		},
+		{
+			name: "too large", body: `{"name":"` + strings.Repeat("a", 100) + `"}`, opts: DecodeOptions{MaxBodySize: 50}, status: http.StatusRequestEntityTooLarge,
+			check: func(t *testing.T, err error) {
+				var e *BodyTooLargeError
+				require.True(t, errors.As(err, &e))
+				require.Equal(t, int64(50), e.Limit)
+			},
+		},
+		{
+			name: "too large trailing data", body: `{}` + strings.Repeat(" ", 100) + `{}`, opts: DecodeOptions{MaxBodySize: 50}, status: http.StatusRequestEntityTooLarge,
+			check: func(t *testing.T, err error) {
+				var e *BodyTooLargeError
+				require.True(t, errors.As(err, &e))
+			},
+		},
+		{
+			name: "unlimited", body: `{"name":"` + strings.Repeat("a", DefaultMaxBodySize) + `"}`, opts: DecodeOptions{MaxBodySize: -1},
+		},
+		{
+			name: "syntax", body: `{"name": "John",}`, status: http.StatusBadRequest,
+			check: func(t *testing.T, err error) {
+				var e *SyntaxError
+				require.True(t, errors.As(err, &e))
+				require.Equal(t, int64(17), e.Offset)
+				require.Equal(t, "invalid JSON at offset 17: invalid character '}' looking for beginning of object key string", err.Error())
+			},
+		},
+		{
+			name: "empty", body: ``, status: http.StatusBadRequest,
+			check: func(t *testing.T, err error) {
+				require.Equal(t, "invalid JSON at offset 0: empty body", err.Error())
+			},
+		},
+		{
+			name: "unexpected end", body: `{"name": "Jo`, status: http.StatusBadRequest,
+			check: func(t *testing.T, err error) {
+				var e *SyntaxError
+				require.True(t, errors.As(err, &e))
+				require.Contains(t, err.Error(), "unexpected end of JSON input")
+			},
+		},
+		{
+			name: "trailing data", body: `{"name":"John"} {"name":"Jane"}`, status: http.StatusBadRequest,
+			check: func(t *testing.T, err error) {
+				var e *SyntaxError
+				require.True(t, errors.As(err, &e))
+				require.Contains(t, err.Error(), "unexpected data after JSON value")
+			},
+		},
+		{
+			name: "trailing garbage", body: `{"name":"John"}}`, status: http.StatusBadRequest,
+		},
+		{
+			name: "type mismatch", body: `{"address":{"zip":"12345"}}`, status: http.StatusBadRequest,
+			check: func(t *testing.T, err error) {
+				var e *TypeError
+				require.True(t, errors.As(err, &e))
+				require.Equal(t, "address.zip", e.Field)
+				require.Equal(t, "string", e.Value)
+				require.Equal(t, "int", e.Type)
+				require.Equal(t, `field "address.zip": cannot use JSON string as int`, err.Error())
+			},
+		},
+		{
+			name: "unknown field", body: `{"name":"John","extra":true}`, opts: DecodeOptions{DisallowUnknownFields: true}, status: http.StatusBadRequest,
+			check: func(t *testing.T, err error) {
+				var e *UnknownFieldError
+				require.True(t, errors.As(err, &e))
+				require.Equal(t, "extra", e.Field)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := DecodeWith[decodeTarget](newRequest(tc.body, tc.ctype), tc.opts)
+			if tc.status == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			require.True(t, errors.Is(err, ErrUnmarshal))
+			var statusCoder StatusCoder
+			require.True(t, errors.As(err, &statusCoder))
+			require.Equal(t, tc.status, statusCoder.StatusCode())
+			if tc.check != nil {
+				tc.check(t, err)
+			}
+		})
+	}
+}
+
+func TestDecode_ErrorResponse(t *testing.T) {
+	testCases := []struct {
+		name   string
+		body   string
+		ctype  string
+		status int
+		code   string
+	}{
+		{name: "syntax", body: `{`, ctype: "application/json", status: http.StatusBadRequest, code: "UNMARSHAL_ERROR"},
+		{name: "media type", body: `{}`, ctype: "application/xml", status: http.StatusUnsupportedMediaType, code: "UNSUPPORTED_MEDIA_TYPE"},
+		{name: "too large", body: strings.Repeat(" ", DefaultMaxBodySize+1), ctype: "application/json", status: http.StatusRequestEntityTooLarge, code: "REQUEST_ENTITY_TOO_LARGE"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.ctype)
+			w := httptest.NewRecorder()
+
+			_, err := Decode[decodeTarget](req)
+			ErrorResponse(w, req, http.StatusBadRequest, err, "")
+
+			require.Equal(t, tc.status, w.Code)
+			var response HttpError
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			require.Equal(t, tc.code, response.Err)
+			require.Equal(t, err.Error(), response.Message)
+		})
+	}
+
+	t.Run("custom message", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(`{`))
+		w := httptest.NewRecorder()
+
+		_, err := Decode[decodeTarget](req)
+		ErrorResponse(w, req, http.StatusBadRequest, err, "bad body")
+
+		var response HttpError
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Equal(t, "UNMARSHAL_ERROR", response.Err)
+		require.Equal(t, "bad body", response.Message)
+	})
+}
@@ -1,6 +1,6 @@
 module github.com/pkgz/rest
 
-go 1.19
+go 1.24
 
 require (
 	github.com/go-chi/chi/v5 v5.0.3
@@ -0,0 +1,74 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+)
+
+const unixPrefix = "unix:"
+
+// isUnixAddress - checks if address points to the unix socket
+func isUnixAddress(address string) bool {
+	return strings.HasPrefix(address, unixPrefix)
+}
+
+// listen - returns pre-opened listener if provided, otherwise opens unix socket or tcp listener for the address
+func (s *Server) listen(ln net.Listener, address string, port int) (net.Listener, error) {
+	if ln != nil {
+		return ln, nil
+	}
+	if isUnixAddress(address) {
+		return listenUnix(strings.TrimPrefix(address, unixPrefix), s.SocketMode)
+	}
+	return net.Listen("tcp", fmt.Sprintf("%s:%d", address, port))
+}
+
+// listenUnix - opens unix socket with provided permissions, a stale socket file left by the crashed process is removed.
+// The socket file is removed when the listener is closed.
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(true)
+
+	if err := os.Chmod(path, mode); err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("set unix socket permissions, %w", err)
+	}
+
+	return ln, nil
+}
+
+// removeStaleSocket - removes socket file if nobody listens on it
+func removeStaleSocket(path string) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a unix socket", path)
+	}
+
+	conn, err := net.Dial("unix", path)
+	if err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("unix socket %s is in use", path)
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		return err
+	}
+
+	return os.Remove(path)
+}
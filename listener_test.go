@@ -0,0 +1,172 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestServer_RunContext_unix(t *testing.T) {
+	t.Run("serve on unix socket", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rest.sock")
+
+		srv := &Server{
+			Address:    "unix:" + path,
+			SocketMode: 0o600,
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error)
+		go func() {
+			done <- srv.RunContext(ctx, nil)
+		}()
+		waitForUnixSocket(t, path)
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+		resp, err := unixClient(path).Get("http://rest/ping")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		cancel()
+		require.NoError(t, <-done)
+
+		_, err = os.Stat(path)
+		require.True(t, os.IsNotExist(err), "socket file must be removed on shutdown")
+	})
+
+	t.Run("stale socket file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rest.sock")
+
+		ln, err := net.Listen("unix", path)
+		require.NoError(t, err)
+		ln.(*net.UnixListener).SetUnlinkOnClose(false)
+		require.NoError(t, ln.Close())
+
+		srv := &Server{
+			Address: "unix:" + path,
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error)
+		go func() {
+			done <- srv.RunContext(ctx, nil)
+		}()
+		waitForUnixSocket(t, path)
+
+		resp, err := unixClient(path).Get("http://rest/ping")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	t.Run("socket in use", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rest.sock")
+
+		ln, err := net.Listen("unix", path)
+		require.NoError(t, err)
+		defer func() { _ = ln.Close() }()
+
+		srv := &Server{
+			Address: "unix:" + path,
+		}
+		require.Error(t, srv.RunContext(context.Background(), nil))
+	})
+
+	t.Run("not a socket", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rest.sock")
+		require.NoError(t, os.WriteFile(path, []byte("data"), 0o600))
+
+		srv := &Server{
+			Address: "unix:" + path,
+		}
+		require.Error(t, srv.RunContext(context.Background(), nil))
+
+		_, err := os.Stat(path)
+		require.NoError(t, err)
+	})
+
+	t.Run("https on the same socket", func(t *testing.T) {
+		certPath, keyPath := generateCert(t, t.TempDir(), "server")
+
+		srv := &Server{
+			Address: "unix:" + filepath.Join(t.TempDir(), "rest.sock"),
+			SSL: &SSLConfig{
+				CertPath: certPath,
+				KeyPath:  keyPath,
+			},
+		}
+		require.Error(t, srv.RunContext(context.Background(), nil))
+	})
+}
+
+func TestServer_RunContext_listener(t *testing.T) {
+	certPath, keyPath := generateCert(t, t.TempDir(), "server")
+
+	httpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	httpsListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := &Server{
+		Listener: httpListener,
+		SSL: &SSLConfig{
+			Listener: httpsListener,
+			CertPath: certPath,
+			KeyPath:  keyPath,
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error)
+	go func() {
+		done <- srv.RunContext(ctx, nil)
+	}()
+	waitForServer(t, httpListener.Addr().String())
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/ping", httpListener.Addr()))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = insecureClient().Get(fmt.Sprintf("https://%s/ping", httpsListener.Addr()))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+// waitForUnixSocket - waits until server accepts connections on the unix socket
+func waitForUnixSocket(t *testing.T, path string) {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		if conn, err := net.Dial("unix", path); err == nil {
+			_ = conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("server on %s is not started", path)
+}
+
+// unixClient - http client which sends all requests to the unix socket
+func unixClient(path string) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			},
+		},
+	}
+}
@@ -0,0 +1,213 @@
+package rest
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"io"
+	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// LogEntry - details of the served request
+type LogEntry struct {
+	Level     slog.Level // info for 1xx-3xx, warn for 4xx and slow requests, error for 5xx
+	Method    string
+	Path      string // sanitized request uri
+	Route     string // chi route pattern, empty if the request is not routed by chi
+	Status    int
+	Bytes     int
+	Duration  time.Duration
+	Addr      string
+	RequestID string
+	TraceID   string // empty if the request has no span context
+	SpanID    string
+	UserAgent string
+	Headers   http.Header // logged request headers with masked secrets
+}
+
+// LogSink - destination of the request log entries
+type LogSink interface {
+	Log(ctx context.Context, entry LogEntry)
+}
+
+// LogSinkFunc - adapter to use ordinary function as LogSink
+type LogSinkFunc func(ctx context.Context, entry LogEntry)
+
+// Log - calls f(ctx, entry)
+func (f LogSinkFunc) Log(ctx context.Context, entry LogEntry) {
+	f(ctx, entry)
+}
+
+// LoggerConfig - settings of the structured request logger
+type LoggerConfig struct {
+	Sink      LogSink          // destination of the log entries, slog.Default() if nil
+	Headers   []string         // request headers included into the log entries
+	Redaction *RedactionPolicy // secrets masked in the logged path and headers, DefaultRedactionPolicy if nil
+
+	SkipPaths     []string           // paths which are not logged, like probes
+	SamplePaths   map[string]float64 // share of logged requests per path, overrides SampleRate
+	SampleRate    float64            // share of logged requests with status below 400, all of them are logged if zero
+	SlowThreshold time.Duration      // requests slower than threshold are logged with warn level, disabled if zero
+}
+
+// sampled - decides if the request is logged. Server errors and slow requests are always logged.
+func (c LoggerConfig) sampled(path string, entry LogEntry, skip map[string]bool) bool {
+	if entry.Status >= http.StatusInternalServerError || c.slow(entry) {
+		return true
+	}
+	if skip[path] {
+		return false
+	}
+	if entry.Status >= http.StatusBadRequest {
+		return true
+	}
+
+	rate, ok := c.SamplePaths[path]
+	if !ok {
+		if c.SampleRate == 0 {
+			return true
+		}
+		rate = c.SampleRate
+	}
+	return rand.Float64() < rate
+}
+
+// slow - checks if the request took longer than SlowThreshold
+func (c LoggerConfig) slow(entry LogEntry) bool {
+	return c.SlowThreshold > 0 && entry.Duration > c.SlowThreshold
+}
+
+// level - returns log level for the entry by status class and duration
+func (c LoggerConfig) level(entry LogEntry) slog.Level {
+	switch {
+	case entry.Status >= http.StatusInternalServerError:
+		return slog.LevelError
+	case entry.Status >= http.StatusBadRequest, c.slow(entry):
+		return slog.LevelWarn
+	}
+	return slog.LevelInfo
+}
+
+type slogSink struct {
+	logger *slog.Logger
+}
+
+// SlogSink - writes log entries as records of the slog logger
+func SlogSink(logger *slog.Logger) LogSink {
+	return &slogSink{logger: logger}
+}
+
+// JSONSink - writes log entries as JSON lines
+func JSONSink(w io.Writer) LogSink {
+	return SlogSink(slog.New(slog.NewJSONHandler(w, nil)))
+}
+
+// LogfmtSink - writes log entries as logfmt lines
+func LogfmtSink(w io.Writer) LogSink {
+	return SlogSink(slog.New(slog.NewTextHandler(w, nil)))
+}
+
+// Log - writes the entry to the slog logger
+func (s *slogSink) Log(ctx context.Context, entry LogEntry) {
+	attrs := []slog.Attr{
+		slog.String("method", entry.Method),
+		slog.String("path", entry.Path),
+		slog.String("route", entry.Route),
+		slog.Int("status", entry.Status),
+		slog.Int("bytes", entry.Bytes),
+		slog.Duration("duration", entry.Duration),
+		slog.String("addr", entry.Addr),
+		slog.String("request_id", entry.RequestID),
+		slog.String("user_agent", entry.UserAgent),
+	}
+	if entry.TraceID != "" {
+		attrs = append(attrs, slog.String("trace_id", entry.TraceID), slog.String("span_id", entry.SpanID))
+	}
+	if len(entry.Headers) > 0 {
+		headers := make([]any, 0, len(entry.Headers))
+		for _, name := range slices.Sorted(maps.Keys(entry.Headers)) {
+			headers = append(headers, slog.String(name, strings.Join(entry.Headers.Values(name), ", ")))
+		}
+		attrs = append(attrs, slog.Group("headers", headers...))
+	}
+
+	s.logger.LogAttrs(ctx, entry.Level, "http request", attrs...)
+}
+
+// LoggerWithConfig - log all requests as structured records to the configured sink
+func LoggerWithConfig(cfg LoggerConfig) func(http.Handler) http.Handler {
+	sink := cfg.Sink
+	if sink == nil {
+		sink = SlogSink(slog.Default())
+	}
+	redaction := cfg.Redaction
+	if redaction == nil {
+		redaction = DefaultRedactionPolicy
+	}
+	skip := make(map[string]bool, len(cfg.SkipPaths))
+	for _, path := range cfg.SkipPaths {
+		skip[path] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+
+			defer func() {
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+
+				var route string
+				if rctx := chi.RouteContext(r.Context()); rctx != nil {
+					route = rctx.RoutePattern()
+				}
+
+				entry := LogEntry{
+					Method:    r.Method,
+					Path:      requestURI(r, redaction),
+					Route:     route,
+					Status:    status,
+					Bytes:     ww.BytesWritten(),
+					Duration:  time.Since(start),
+					Addr:      GetAddr(r),
+					RequestID: GetRequestID(r.Context()),
+					UserAgent: r.UserAgent(),
+				}
+
+				var path string
+				if r.URL != nil {
+					path = r.URL.Path
+				}
+				if !cfg.sampled(path, entry, skip) {
+					return
+				}
+				entry.Level = cfg.level(entry)
+				if sc, ok := requestSpanContext(r); ok {
+					entry.TraceID, entry.SpanID = sc.TraceID, sc.SpanID
+				}
+				if len(cfg.Headers) > 0 {
+					headers := http.Header{}
+					for _, name := range cfg.Headers {
+						if values := r.Header.Values(name); len(values) > 0 {
+							headers[http.CanonicalHeaderKey(name)] = values
+						}
+					}
+					entry.Headers = redaction.Header(headers)
+				}
+
+				sink.Log(r.Context(), entry)
+			}()
+
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
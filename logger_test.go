@@ -0,0 +1,205 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLoggerWithConfig(t *testing.T) {
+	t.Run("entry", func(t *testing.T) {
+		var entries []LogEntry
+		sink := LogSinkFunc(func(_ context.Context, entry LogEntry) {
+			entries = append(entries, entry)
+		})
+
+		router := chi.NewRouter()
+		router.Use(middleware.RequestID)
+		router.Use(LoggerWithConfig(LoggerConfig{Sink: sink}))
+		router.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte("hello"))
+		})
+
+		req := httptest.NewRequest("GET", "/users/42?jwt=secret", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		req.Header.Set("User-Agent", "test-agent")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		require.Len(t, entries, 1)
+		entry := entries[0]
+		require.Equal(t, "GET", entry.Method)
+		require.Equal(t, "/users/42?jwt=***", entry.Path)
+		require.Equal(t, "/users/{id}", entry.Route)
+		require.Equal(t, http.StatusCreated, entry.Status)
+		require.Equal(t, 5, entry.Bytes)
+		require.Equal(t, "192.168.1.1", entry.Addr)
+		require.NotEmpty(t, entry.RequestID)
+		require.Equal(t, "test-agent", entry.UserAgent)
+		require.True(t, entry.Duration > 0)
+	})
+
+	t.Run("default status", func(t *testing.T) {
+		var entry LogEntry
+		handler := LoggerWithConfig(LoggerConfig{Sink: LogSinkFunc(func(_ context.Context, e LogEntry) {
+			entry = e
+		})})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		require.Equal(t, http.StatusOK, entry.Status)
+		require.Empty(t, entry.Route)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		handler := LoggerWithConfig(LoggerConfig{Sink: JSONSink(&buf)})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/test", nil))
+
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+		require.Equal(t, "http request", record["msg"])
+		require.Equal(t, "POST", record["method"])
+		require.Equal(t, "/test", record["path"])
+		require.Equal(t, float64(http.StatusNotFound), record["status"])
+		require.Contains(t, record, "duration")
+		require.Contains(t, record, "user_agent")
+	})
+
+	t.Run("logfmt", func(t *testing.T) {
+		var buf bytes.Buffer
+		handler := LoggerWithConfig(LoggerConfig{Sink: LogfmtSink(&buf)})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+		require.Contains(t, buf.String(), `msg="http request"`)
+		require.Contains(t, buf.String(), "method=GET")
+		require.Contains(t, buf.String(), "path=/test")
+		require.Contains(t, buf.String(), "status=200")
+	})
+
+	t.Run("default sink", func(t *testing.T) {
+		var buf bytes.Buffer
+		defaultLogger := slog.Default()
+		slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+		defer slog.SetDefault(defaultLogger)
+
+		handler := LoggerWithConfig(LoggerConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+		require.Contains(t, buf.String(), `"path":"/test"`)
+	})
+}
+
+func TestLoggerWithConfig_levels(t *testing.T) {
+	testCases := []struct {
+		name   string
+		status int
+		level  slog.Level
+	}{
+		{"ok", http.StatusOK, slog.LevelInfo},
+		{"redirect", http.StatusFound, slog.LevelInfo},
+		{"bad request", http.StatusBadRequest, slog.LevelWarn},
+		{"not found", http.StatusNotFound, slog.LevelWarn},
+		{"internal error", http.StatusInternalServerError, slog.LevelError},
+		{"unavailable", http.StatusServiceUnavailable, slog.LevelError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var entry LogEntry
+			handler := LoggerWithConfig(LoggerConfig{Sink: LogSinkFunc(func(_ context.Context, e LogEntry) {
+				entry = e
+			})})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+			require.Equal(t, tc.level, entry.Level)
+		})
+	}
+
+	t.Run("slow request", func(t *testing.T) {
+		var entry LogEntry
+		handler := LoggerWithConfig(LoggerConfig{
+			SlowThreshold: time.Millisecond,
+			Sink: LogSinkFunc(func(_ context.Context, e LogEntry) {
+				entry = e
+			}),
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(5 * time.Millisecond)
+		}))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+		require.Equal(t, slog.LevelWarn, entry.Level)
+	})
+
+	t.Run("slog level", func(t *testing.T) {
+		var buf bytes.Buffer
+		handler := LoggerWithConfig(LoggerConfig{Sink: JSONSink(&buf)})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+		require.Contains(t, buf.String(), `"level":"ERROR"`)
+	})
+}
+
+func TestLoggerWithConfig_sampling(t *testing.T) {
+	serve := func(cfg LoggerConfig, path string, status int, delay time.Duration, n int) int {
+		var count int
+		cfg.Sink = LogSinkFunc(func(_ context.Context, _ LogEntry) {
+			count++
+		})
+		handler := LoggerWithConfig(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(delay)
+			w.WriteHeader(status)
+		}))
+		for i := 0; i < n; i++ {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+		}
+		return count
+	}
+
+	t.Run("skip paths", func(t *testing.T) {
+		cfg := LoggerConfig{SkipPaths: []string{"/ping", "/liveness"}}
+
+		require.Equal(t, 0, serve(cfg, "/ping", http.StatusOK, 0, 10))
+		require.Equal(t, 0, serve(cfg, "/liveness?full=1", http.StatusOK, 0, 10))
+		require.Equal(t, 10, serve(cfg, "/users", http.StatusOK, 0, 10))
+		require.Equal(t, 10, serve(cfg, "/ping", http.StatusInternalServerError, 0, 10))
+	})
+
+	t.Run("skipped slow request", func(t *testing.T) {
+		cfg := LoggerConfig{SkipPaths: []string{"/ping"}, SlowThreshold: time.Millisecond}
+		require.Equal(t, 1, serve(cfg, "/ping", http.StatusOK, 5*time.Millisecond, 1))
+	})
+
+	t.Run("sample rate", func(t *testing.T) {
+		cfg := LoggerConfig{SampleRate: 0.1}
+
+		count := serve(cfg, "/users", http.StatusOK, 0, 1000)
+		require.True(t, count > 20 && count < 300, "logged %d of 1000", count)
+		require.Equal(t, 100, serve(cfg, "/users", http.StatusNotFound, 0, 100))
+		require.Equal(t, 100, serve(cfg, "/users", http.StatusInternalServerError, 0, 100))
+	})
+
+	t.Run("sample paths", func(t *testing.T) {
+		cfg := LoggerConfig{
+			SampleRate:  0.5,
+			SamplePaths: map[string]float64{"/readiness": 0, "/important": 1},
+		}
+
+		require.Equal(t, 0, serve(cfg, "/readiness", http.StatusOK, 0, 100))
+		require.Equal(t, 100, serve(cfg, "/important", http.StatusOK, 0, 100))
+		require.Equal(t, 100, serve(cfg, "/readiness", http.StatusServiceUnavailable, 0, 100))
+	})
+}
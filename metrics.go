@@ -0,0 +1,217 @@
+package rest
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultDurationBuckets - upper bounds of the request duration histogram in seconds
+var DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// DefaultSizeBuckets - upper bounds of the response size histogram in bytes
+var DefaultSizeBuckets = []float64{100, 1000, 10000, 100000, 1e6, 1e7, 1e8}
+
+// Metrics - collects request count, latency, response size and in-flight requests
+// and exposes them in the Prometheus text format. Zero value is ready to use.
+type Metrics struct {
+	Namespace       string    // prefix of the metric names, http by default
+	DurationBuckets []float64 // DefaultDurationBuckets if empty
+	SizeBuckets     []float64 // DefaultSizeBuckets if empty
+
+	inFlight atomic.Int64
+	series   map[metricLabels]*metricSeries
+	mu       sync.Mutex
+}
+
+type metricLabels struct {
+	method string
+	route  string
+	status string
+}
+
+type metricSeries struct {
+	count    uint64
+	duration histogram
+	size     histogram
+}
+
+type histogram struct {
+	counts []uint64 // observations per bucket, the last one is +Inf
+	sum    float64
+}
+
+func (h *histogram) observe(buckets []float64, v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(buckets)+1)
+	}
+	i, _ := slices.BinarySearch(buckets, v)
+	h.counts[i]++
+	h.sum += v
+}
+
+// Middleware - records metrics of the requests labelled by method, chi route pattern and status class
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+
+		m.inFlight.Add(1)
+		defer m.inFlight.Add(-1)
+
+		defer func() {
+			// panicking requests are recorded too, as 5xx unless the handler has written another status
+			rvr := recover()
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+				if rvr != nil {
+					status = http.StatusInternalServerError
+				}
+			}
+			route := "unmatched"
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+
+			m.observe(metricLabels{
+				method: metricMethod(r.Method),
+				route:  route,
+				status: fmt.Sprintf("%dxx", status/100),
+			}, time.Since(start), ww.BytesWritten())
+
+			if rvr != nil {
+				panic(rvr)
+			}
+		}()
+
+		next.ServeHTTP(ww, r)
+	}
+	return http.HandlerFunc(fn)
+}
+
+// ServeHTTP - writes collected metrics in the Prometheus text exposition format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(m.expose())
+}
+
+func (m *Metrics) observe(labels metricLabels, duration time.Duration, size int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.series == nil {
+		m.series = map[metricLabels]*metricSeries{}
+	}
+	s, ok := m.series[labels]
+	if !ok {
+		s = &metricSeries{}
+		m.series[labels] = s
+	}
+	s.count++
+	s.duration.observe(m.durationBuckets(), duration.Seconds())
+	s.size.observe(m.sizeBuckets(), float64(size))
+}
+
+func (m *Metrics) expose() []byte {
+	ns := m.Namespace
+	if ns == "" {
+		ns = "http"
+	}
+	buf := &bytes.Buffer{}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]metricLabels, 0, len(m.series))
+	for k := range m.series {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b metricLabels) int {
+		return strings.Compare(a.route+"\x00"+a.method+"\x00"+a.status, b.route+"\x00"+b.method+"\x00"+b.status)
+	})
+
+	fmt.Fprintf(buf, "# HELP %s_requests_total Total number of HTTP requests.\n", ns)
+	fmt.Fprintf(buf, "# TYPE %s_requests_total counter\n", ns)
+	for _, k := range keys {
+		fmt.Fprintf(buf, "%s_requests_total{%s} %d\n", ns, k, m.series[k].count)
+	}
+
+	fmt.Fprintf(buf, "# HELP %s_request_duration_seconds Duration of HTTP requests in seconds.\n", ns)
+	fmt.Fprintf(buf, "# TYPE %s_request_duration_seconds histogram\n", ns)
+	for _, k := range keys {
+		writeHistogram(buf, ns+"_request_duration_seconds", k, m.durationBuckets(), m.series[k].duration)
+	}
+
+	fmt.Fprintf(buf, "# HELP %s_response_size_bytes Size of HTTP responses in bytes.\n", ns)
+	fmt.Fprintf(buf, "# TYPE %s_response_size_bytes histogram\n", ns)
+	for _, k := range keys {
+		writeHistogram(buf, ns+"_response_size_bytes", k, m.sizeBuckets(), m.series[k].size)
+	}
+
+	fmt.Fprintf(buf, "# HELP %s_requests_in_flight Number of HTTP requests being served.\n", ns)
+	fmt.Fprintf(buf, "# TYPE %s_requests_in_flight gauge\n", ns)
+	fmt.Fprintf(buf, "%s_requests_in_flight %d\n", ns, m.inFlight.Load())
+
+	return buf.Bytes()
+}
+
+func (m *Metrics) durationBuckets() []float64 {
+	if len(m.DurationBuckets) == 0 {
+		return DefaultDurationBuckets
+	}
+	return m.DurationBuckets
+}
+
+func (m *Metrics) sizeBuckets() []float64 {
+	if len(m.SizeBuckets) == 0 {
+		return DefaultSizeBuckets
+	}
+	return m.SizeBuckets
+}
+
+// String - formats labels for the exposition format
+func (l metricLabels) String() string {
+	return fmt.Sprintf(`method="%s",route="%s",status="%s"`, escapeLabel(l.method), escapeLabel(l.route), l.status)
+}
+
+// writeHistogram - writes cumulative buckets, sum and count of the histogram
+func writeHistogram(buf *bytes.Buffer, name string, labels metricLabels, buckets []float64, h histogram) {
+	var total uint64
+	for i, bound := range buckets {
+		if h.counts != nil {
+			total += h.counts[i]
+		}
+		fmt.Fprintf(buf, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, strconv.FormatFloat(bound, 'g', -1, 64), total)
+	}
+	if h.counts != nil {
+		total += h.counts[len(buckets)]
+	}
+	fmt.Fprintf(buf, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, total)
+	fmt.Fprintf(buf, "%s_sum{%s} %s\n", name, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(buf, "%s_count{%s} %d\n", name, labels, total)
+}
+
+// metricMethod - keeps the label cardinality bounded for arbitrary request methods
+func metricMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+func escapeLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
@@ -0,0 +1,153 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	scrape := func(t *testing.T, m *Metrics) string {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "text/plain; version=0.0.4; charset=utf-8", w.Header().Get("Content-Type"))
+		return w.Body.String()
+	}
+
+	t.Run("requests", func(t *testing.T) {
+		m := &Metrics{}
+		router := chi.NewRouter()
+		router.Use(m.Middleware)
+		router.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("hello"))
+		})
+		router.Post("/users", func(w http.ResponseWriter, r *http.Request) {
+			ErrorResponse(w, r, http.StatusBadRequest, nil, "")
+		})
+
+		for i := 0; i < 3; i++ {
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", fmt.Sprintf("/users/%d", i), nil))
+		}
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/users", nil))
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/unknown", nil))
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PROPFIND", "/users", nil))
+
+		out := scrape(t, m)
+		require.Contains(t, out, "# TYPE http_requests_total counter\n")
+		require.Contains(t, out, `http_requests_total{method="GET",route="/users/{id}",status="2xx"} 3`+"\n")
+		require.Contains(t, out, `http_requests_total{method="POST",route="/users",status="4xx"} 1`+"\n")
+		require.Contains(t, out, `http_requests_total{method="GET",route="unmatched",status="4xx"} 1`+"\n")
+		require.Contains(t, out, `http_requests_total{method="OTHER",route="unmatched",status="4xx"} 1`+"\n")
+		require.NotContains(t, out, "/users/1")
+
+		require.Contains(t, out, "# TYPE http_request_duration_seconds histogram\n")
+		require.Contains(t, out, `http_request_duration_seconds_bucket{method="GET",route="/users/{id}",status="2xx",le="10"} 3`+"\n")
+		require.Contains(t, out, `http_request_duration_seconds_bucket{method="GET",route="/users/{id}",status="2xx",le="+Inf"} 3`+"\n")
+		require.Contains(t, out, `http_request_duration_seconds_count{method="GET",route="/users/{id}",status="2xx"} 3`+"\n")
+
+		require.Contains(t, out, "# TYPE http_response_size_bytes histogram\n")
+		require.Contains(t, out, `http_response_size_bytes_bucket{method="GET",route="/users/{id}",status="2xx",le="100"} 3`+"\n")
+		require.Contains(t, out, `http_response_size_bytes_sum{method="GET",route="/users/{id}",status="2xx"} 15`+"\n")
+
+		require.Contains(t, out, "# TYPE http_requests_in_flight gauge\nhttp_requests_in_flight 0\n")
+	})
+
+	t.Run("custom namespace and buckets", func(t *testing.T) {
+		m := &Metrics{Namespace: "api", SizeBuckets: []float64{1, 10}}
+		m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("hello"))
+		})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+		out := scrape(t, m)
+		require.Contains(t, out, `api_requests_total{method="GET",route="unmatched",status="2xx"} 1`)
+		require.Equal(t, 3, strings.Count(out, "api_response_size_bytes_bucket"))
+		require.Contains(t, out, `api_response_size_bytes_bucket{method="GET",route="unmatched",status="2xx",le="1"} 0`)
+		require.Contains(t, out, `api_response_size_bytes_bucket{method="GET",route="unmatched",status="2xx",le="10"} 1`)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		m := &Metrics{}
+		router := chi.NewRouter()
+		router.Use(m.Middleware)
+		router.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		})
+
+		func() {
+			defer func() {
+				require.Equal(t, "boom", recover())
+			}()
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/panic", nil))
+			t.Fatal("panic is not propagated")
+		}()
+
+		out := scrape(t, m)
+		require.Contains(t, out, `http_requests_total{method="GET",route="/panic",status="5xx"} 1`+"\n")
+		require.Contains(t, out, "http_requests_in_flight 0\n")
+	})
+
+	t.Run("in flight", func(t *testing.T) {
+		m := &Metrics{}
+		started, release := make(chan struct{}), make(chan struct{})
+		handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			started <- struct{}{}
+			<-release
+		}))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+			}()
+			<-started
+		}
+
+		require.Contains(t, scrape(t, m), "http_requests_in_flight 2\n")
+		close(release)
+		wg.Wait()
+		require.Contains(t, scrape(t, m), "http_requests_in_flight 0\n")
+	})
+
+	t.Run("label escaping", func(t *testing.T) {
+		require.Equal(t, `method="GET",route="/a\"b\\c\nd",status="2xx"`, metricLabels{method: "GET", route: "/a\"b\\c\nd", status: "2xx"}.String())
+	})
+
+	t.Run("server default router", func(t *testing.T) {
+		srv := &Server{
+			Port:    freePort(t),
+			Metrics: &Metrics{},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error)
+		go func() {
+			done <- srv.RunContext(ctx, nil)
+		}()
+		waitForServer(t, fmt.Sprintf("localhost:%d", srv.Port))
+
+		resp, err := http.Get(fmt.Sprintf("http://localhost:%d/ping", srv.Port))
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+
+		resp, err = http.Get(fmt.Sprintf("http://localhost:%d/metrics", srv.Port))
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Contains(t, string(body), `http_requests_total{method="GET",route="/ping",status="2xx"} 1`)
+
+		cancel()
+		require.NoError(t, <-done)
+	})
+}
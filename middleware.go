@@ -1,10 +1,12 @@
 package rest
 
 import (
+	"fmt"
 	"github.com/go-chi/chi/v5/middleware"
 	"log"
 	"net/http"
 	"net/url"
+	"runtime/debug"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -22,19 +24,15 @@ func Logger(next http.Handler) http.Handler {
 				statusCode = 200
 			}
 
-			var uri string
-			if r.URL != nil {
-				uri = r.URL.String()
-				uri = SanitizeURL(uri)
-				if qun, e := url.QueryUnescape(uri); e == nil {
-					uri = qun
-				}
-			} else {
-				uri = "<nil>"
-			}
-
 			duration := time.Now().Sub(start)
-			log.Printf("[DEBUG] %s - %s - %s - %v - %v", r.Method, uri, GetAddr(r), statusCode, duration)
+			msg := fmt.Sprintf("[DEBUG] %s - %s - %s - %v - %v", r.Method, requestURI(r, DefaultRedactionPolicy), GetAddr(r), statusCode, duration)
+			if id := GetRequestID(r.Context()); id != "" {
+				msg += " - " + id
+			}
+			if sc, ok := requestSpanContext(r); ok {
+				msg += fmt.Sprintf(" - trace_id=%s span_id=%s", sc.TraceID, sc.SpanID)
+			}
+			log.Print(msg)
 		}()
 
 		next.ServeHTTP(ww, r)
@@ -42,6 +40,48 @@ func Logger(next http.Handler) http.Handler {
 	return http.HandlerFunc(fn)
 }
 
+// Recoverer - recovers from panics in handlers, logs the panic with the stack and responds with 500 HttpError.
+// If the response is already started, the connection is aborted with http.ErrAbortHandler.
+// withStack adds the panic value and the stack to the response, use it only in development.
+func Recoverer(withStack bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			defer func() {
+				rvr := recover()
+				if rvr == nil {
+					return
+				}
+				if rvr == http.ErrAbortHandler {
+					panic(rvr)
+				}
+
+				stack := debug.Stack()
+				log.Printf("[ERROR] panic: %v - %s - %s - %s\n%s", rvr, r.Method, requestURI(r, DefaultRedactionPolicy), GetAddr(r), stack)
+
+				if r.Header.Get("Connection") == "Upgrade" {
+					return
+				}
+				if ww.Status() != 0 {
+					// response is already started, abort it like net/http does instead of ending it cleanly
+					panic(http.ErrAbortHandler)
+				}
+				if !withStack {
+					ErrorResponse(ww, r, http.StatusInternalServerError, nil, "")
+					return
+				}
+
+				_, err := resolveHttpError(r, http.StatusInternalServerError, nil, fmt.Sprintf("panic: %v", rvr))
+				err.Stack = string(stack)
+				renderError(ww, r, http.StatusInternalServerError, nil, err)
+			}()
+
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
+
 // Readiness - middleware for the readiness probe
 func Readiness(endpoint string, isReady *atomic.Value) func(http.Handler) http.Handler {
 	return func(h http.Handler) http.Handler {
@@ -61,23 +101,20 @@ func Readiness(endpoint string, isReady *atomic.Value) func(http.Handler) http.H
 	}
 }
 
-// SanitizeURL replaces JWT token values with asterisks in the given URL
-func SanitizeURL(uri string) string {
-	parsedURL, err := url.Parse(uri)
-	if err != nil {
-		return uri
+// requestURI - returns sanitized and unescaped request uri for logs
+func requestURI(r *http.Request, policy *RedactionPolicy) string {
+	if r.URL == nil {
+		return "<nil>"
 	}
-	query := parsedURL.Query()
-
-	if jwtToken := query.Get("jwt"); jwtToken != "" {
-		query.Del("jwt")
-		encodedQuery := query.Encode()
-		if encodedQuery != "" {
-			parsedURL.RawQuery = encodedQuery + "&jwt=***"
-		} else {
-			parsedURL.RawQuery = "jwt=***"
-		}
+
+	uri := policy.URL(r.URL.String())
+	if qun, e := url.QueryUnescape(uri); e == nil {
+		uri = qun
 	}
+	return uri
+}
 
-	return parsedURL.String()
+// SanitizeURL replaces secret values with asterisks in the given URL according to the DefaultRedactionPolicy
+func SanitizeURL(uri string) string {
+	return DefaultRedactionPolicy.URL(uri)
 }
@@ -2,11 +2,15 @@ package rest
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/stretchr/testify/require"
+	"io"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync/atomic"
 	"testing"
 )
@@ -115,6 +119,21 @@ func TestLogger(t *testing.T) {
 		require.NotContains(t, logOutput, jwtToken)
 	})
 
+	t.Run("trace context", func(t *testing.T) {
+		var buf bytes.Buffer
+		log.SetOutput(&buf)
+
+		req := httptest.NewRequest("GET", "/traced", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		req = req.WithContext(context.WithValue(req.Context(), requestIDKey, "req-1"))
+
+		Logger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})).ServeHTTP(httptest.NewRecorder(), req)
+
+		require.Contains(t, buf.String(), " - req-1 - trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7")
+	})
+
 	t.Run("malformed URL", func(t *testing.T) {
 		var buf bytes.Buffer
 		log.SetOutput(&buf)
@@ -163,6 +182,92 @@ func TestLogger(t *testing.T) {
 	})
 }
 
+func TestRecoverer(t *testing.T) {
+	originalOutput := log.Writer()
+	defer log.SetOutput(originalOutput)
+
+	panicHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	t.Run("renders error", func(t *testing.T) {
+		var buf bytes.Buffer
+		log.SetOutput(&buf)
+
+		req := httptest.NewRequest("GET", "/panic?token=secret", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set("Uber-Trace-Id", "trace-1")
+		w := httptest.NewRecorder()
+		Recoverer(false)(panicHandler).ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		require.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+
+		var response HttpError
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Equal(t, "INTERNAL_SERVER_ERROR", response.Err)
+		require.Equal(t, "trace-1", response.TraceID)
+		require.Empty(t, response.Message)
+		require.Empty(t, response.Stack)
+
+		logOutput := buf.String()
+		require.Contains(t, logOutput, "[ERROR] panic: boom - GET - /panic?token=*** - 10.0.0.1")
+		require.Contains(t, logOutput, "middleware_test.go")
+		require.NotContains(t, logOutput, "secret")
+	})
+
+	t.Run("with stack", func(t *testing.T) {
+		log.SetOutput(io.Discard)
+
+		w := httptest.NewRecorder()
+		Recoverer(true)(panicHandler).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		var response HttpError
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Equal(t, "INTERNAL_SERVER_ERROR", response.Err)
+		require.Equal(t, "panic: boom", response.Message)
+		require.True(t, strings.Contains(response.Stack, "middleware_test.go"))
+	})
+
+	t.Run("headers already written", func(t *testing.T) {
+		log.SetOutput(io.Discard)
+
+		w := httptest.NewRecorder()
+		defer func() {
+			require.Equal(t, http.ErrAbortHandler, recover())
+			require.Equal(t, http.StatusAccepted, w.Code)
+			require.Equal(t, "partial", w.Body.String())
+		}()
+		Recoverer(false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte("partial"))
+			panic("boom")
+		})).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		t.Fatal("response is not aborted")
+	})
+
+	t.Run("abort handler", func(t *testing.T) {
+		log.SetOutput(io.Discard)
+
+		defer func() {
+			require.Equal(t, http.ErrAbortHandler, recover())
+		}()
+		Recoverer(false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		t.Fatal("panic is not propagated")
+	})
+
+	t.Run("no panic", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		Recoverer(false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			OkResponse(w)
+		})).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
 func TestReadiness(t *testing.T) {
 	t.Run("service unavailable when not ready", func(t *testing.T) {
 		isReady := &atomic.Value{}
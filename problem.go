@@ -0,0 +1,150 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ProblemDetails - switches ErrorResponse and Recoverer to the RFC 9457 application/problem+json format
+var ProblemDetails = false
+
+// Problem - RFC 9457 problem details. Extensions are written as additional top level members.
+type Problem struct {
+	Type       string                 `json:"type,omitempty"`
+	Title      string                 `json:"title,omitempty"`
+	Status     int                    `json:"status,omitempty"`
+	Detail     string                 `json:"detail,omitempty"`
+	Instance   string                 `json:"instance,omitempty"`
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// ProblemType - type and title of the problem for errors matching Err with errors.Is
+type ProblemType struct {
+	Err   error
+	Type  string
+	Title string
+}
+
+// ProblemTypes - problem types of the package errors, the first matching one is used. Errors without type are about:blank.
+var ProblemTypes = []ProblemType{
+	{Err: ErrValidate, Type: "urn:problem-type:validation-error", Title: "Validation failed"},
+	{Err: ErrMissingField, Type: "urn:problem-type:missing-field", Title: "Missing required field"},
+	{Err: ErrUnmarshal, Type: "urn:problem-type:unmarshal-error", Title: "Invalid request body"},
+	{Err: ErrNotFound, Type: "urn:problem-type:not-found", Title: "Resource not found"},
+}
+
+// Error - returns detail or title of the problem
+func (p Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+// MarshalJSON - writes the problem members together with the extensions, extensions can't override the members
+func (p Problem) MarshalJSON() ([]byte, error) {
+	type problem Problem
+	members, err := json.Marshal(problem(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return members, err
+	}
+
+	res := make(map[string]json.RawMessage, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		res[k] = b
+	}
+	if err := json.Unmarshal(members, &res); err != nil {
+		return nil, err
+	}
+	return json.Marshal(res)
+}
+
+// UnmarshalJSON - reads the problem members, unknown members are stored in the extensions
+func (p *Problem) UnmarshalJSON(data []byte) error {
+	type problem Problem
+	if err := json.Unmarshal(data, (*problem)(p)); err != nil {
+		return err
+	}
+
+	var members map[string]interface{}
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	for _, k := range []string{"type", "title", "status", "detail", "instance"} {
+		delete(members, k)
+	}
+	p.Extensions = nil
+	if len(members) > 0 {
+		p.Extensions = members
+	}
+	return nil
+}
+
+// RenderProblem - sends the problem with application/problem+json Content-Type, status 500 is used if the problem has no status
+func RenderProblem(w http.ResponseWriter, problem Problem) {
+	buf := &bytes.Buffer{}
+	if err := json.NewEncoder(buf).Encode(problem); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	code := problem.Status
+	if code == 0 {
+		code = http.StatusInternalServerError
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(code)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// ProblemResponse - write an error as RFC 9457 problem details. HttpError fields are added as extensions:
+// error as code, trace_id, details, stack and debug.
+func ProblemResponse(w http.ResponseWriter, r *http.Request, code int, error error, msg string) {
+	code, err := resolveHttpError(r, code, error, msg)
+	logError(r, code, error, err)
+
+	RenderProblem(w, newProblem(r, code, error, err))
+}
+
+// newProblem - converts HttpError to the problem with the type of the error
+func newProblem(r *http.Request, code int, error error, err HttpError) Problem {
+	problem := Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(code),
+		Status: code,
+		Detail: err.Message,
+		Extensions: map[string]interface{}{
+			"code": err.Err,
+		},
+	}
+	if r.URL != nil {
+		problem.Instance = r.URL.Path
+	}
+
+	for _, pt := range ProblemTypes {
+		if pt.Err != nil && errors.Is(error, pt.Err) {
+			problem.Type, problem.Title = pt.Type, pt.Title
+			break
+		}
+	}
+
+	if err.TraceID != "" {
+		problem.Extensions["trace_id"] = err.TraceID
+	}
+	if len(err.Details) > 0 {
+		problem.Extensions["details"] = err.Details
+	}
+	if err.Stack != "" {
+		problem.Extensions["stack"] = err.Stack
+	}
+	if err.Debug != "" {
+		problem.Extensions["debug"] = err.Debug
+	}
+	return problem
+}
@@ -0,0 +1,151 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProblem_JSON(t *testing.T) {
+	p := Problem{
+		Type:     "https://example.com/probs/out-of-credit",
+		Title:    "You do not have enough credit.",
+		Status:   http.StatusForbidden,
+		Detail:   "Your current balance is 30, but that costs 50.",
+		Instance: "/account/12345/msgs/abc",
+		Extensions: map[string]interface{}{
+			"balance": 30,
+			"title":   "ignored",
+		},
+	}
+
+	b, err := json.Marshal(p)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"type": "https://example.com/probs/out-of-credit",
+		"title": "You do not have enough credit.",
+		"status": 403,
+		"detail": "Your current balance is 30, but that costs 50.",
+		"instance": "/account/12345/msgs/abc",
+		"balance": 30
+	}`, string(b))
+
+	var decoded Problem
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	require.Equal(t, p.Type, decoded.Type)
+	require.Equal(t, p.Status, decoded.Status)
+	require.Equal(t, map[string]interface{}{"balance": float64(30)}, decoded.Extensions)
+
+	b, err = json.Marshal(Problem{Title: "Not Found", Status: http.StatusNotFound})
+	require.NoError(t, err)
+	require.Equal(t, `{"title":"Not Found","status":404}`, string(b))
+
+	require.Equal(t, "Your current balance is 30, but that costs 50.", p.Error())
+	require.Equal(t, "Not Found", Problem{Title: "Not Found"}.Error())
+}
+
+func TestProblemResponse(t *testing.T) {
+	originalOutput := log.Writer()
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(originalOutput)
+
+	readProblem := func(t *testing.T, w *httptest.ResponseRecorder) Problem {
+		require.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+		var p Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+		return p
+	}
+
+	t.Run("generic error", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/users/42?token=secret", nil)
+		req.Header.Set("Uber-Trace-Id", "trace-1")
+		w := httptest.NewRecorder()
+		ProblemResponse(w, req, http.StatusConflict, errors.New("already exists"), "user already exists")
+
+		require.Equal(t, http.StatusConflict, w.Code)
+		p := readProblem(t, w)
+		require.Equal(t, "about:blank", p.Type)
+		require.Equal(t, "Conflict", p.Title)
+		require.Equal(t, http.StatusConflict, p.Status)
+		require.Equal(t, "user already exists", p.Detail)
+		require.Equal(t, "/users/42", p.Instance)
+		require.Equal(t, map[string]interface{}{"code": "CONFLICT", "trace_id": "trace-1"}, p.Extensions)
+	})
+
+	t.Run("sentinel types", func(t *testing.T) {
+		testCases := []struct {
+			err      error
+			wantType string
+		}{
+			{ErrNotFound, "urn:problem-type:not-found"},
+			{fmt.Errorf("user 42, %w", ErrNotFound), "urn:problem-type:not-found"},
+			{ErrUnmarshal, "urn:problem-type:unmarshal-error"},
+			{ErrMissingField, "urn:problem-type:missing-field"},
+			{&ValidationError{Fields: []FieldError{{Field: "name", Rule: "required"}}}, "urn:problem-type:validation-error"},
+			{&SyntaxError{Err: errors.New("empty body")}, "urn:problem-type:unmarshal-error"},
+			{nil, "about:blank"},
+		}
+
+		for _, tc := range testCases {
+			w := httptest.NewRecorder()
+			ProblemResponse(w, httptest.NewRequest("GET", "/", nil), http.StatusBadRequest, tc.err, "")
+			require.Equal(t, tc.wantType, readProblem(t, w).Type, "%v", tc.err)
+		}
+	})
+
+	t.Run("validation details", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(`{"name":""}`))
+		var v struct {
+			Name string `json:"name" validate:"required"`
+		}
+		err := ReadBody(req, &v)
+
+		w := httptest.NewRecorder()
+		ProblemResponse(w, req, http.StatusBadRequest, err, "")
+
+		require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		p := readProblem(t, w)
+		require.Equal(t, "Validation failed", p.Title)
+		require.Equal(t, http.StatusUnprocessableEntity, p.Status)
+		require.Equal(t, "validation failed: name is required", p.Detail)
+		require.Equal(t, "VALIDATION_ERROR", p.Extensions["code"])
+		require.Equal(t, []interface{}{
+			map[string]interface{}{"field": "name", "rule": "required", "message": "is required"},
+		}, p.Extensions["details"])
+	})
+
+	t.Run("global mode", func(t *testing.T) {
+		ProblemDetails = true
+		defer func() { ProblemDetails = false }()
+
+		w := httptest.NewRecorder()
+		ErrorResponse(w, httptest.NewRequest("GET", "/missing", nil), http.StatusNotFound, ErrNotFound, "")
+		require.Equal(t, http.StatusNotFound, w.Code)
+		p := readProblem(t, w)
+		require.Equal(t, "urn:problem-type:not-found", p.Type)
+		require.Equal(t, "/missing", p.Instance)
+
+		w = httptest.NewRecorder()
+		Recoverer(true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		})).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		p = readProblem(t, w)
+		require.Equal(t, "panic: boom", p.Detail)
+		require.NotEmpty(t, p.Extensions["stack"])
+	})
+
+	t.Run("render problem", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		RenderProblem(w, Problem{Title: "Something went wrong"})
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		require.Equal(t, "Something went wrong", readProblem(t, w).Title)
+	})
+}
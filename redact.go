@@ -0,0 +1,128 @@
+package rest
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// RedactionPolicy - defines secrets which are masked in urls and headers before they are logged
+type RedactionPolicy struct {
+	Params        []string         // names of query parameters with secret values, case insensitive
+	ParamPatterns []*regexp.Regexp // patterns of query parameter names with secret values
+	PathPatterns  []*regexp.Regexp // patterns of secrets in the path, capture groups or the whole match are masked
+	Headers       []string         // names of headers with secret values
+	Mask          string           // replacement of the secret values, *** by default
+}
+
+// DefaultRedactionPolicy - policy used by SanitizeURL, Logger, LoggerWithConfig and ErrorResponse
+var DefaultRedactionPolicy = &RedactionPolicy{
+	Params: []string{
+		"jwt", "token", "access_token", "refresh_token", "id_token",
+		"api_key", "apikey", "signature", "sig", "password", "secret", "client_secret",
+	},
+	ParamPatterns: []*regexp.Regexp{
+		regexp.MustCompile(`(?i)(passw|secret|token)`),
+	},
+	Headers: []string{
+		"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key",
+	},
+}
+
+// URL - masks secret query parameters and path segments in the uri
+func (p *RedactionPolicy) URL(uri string) string {
+	parsedURL, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+
+	if len(p.PathPatterns) > 0 {
+		rawPath := parsedURL.EscapedPath()
+		masked := rawPath
+		for _, re := range p.PathPatterns {
+			masked = p.maskMatches(re, masked)
+		}
+		if masked != rawPath {
+			if path, err := url.PathUnescape(masked); err == nil {
+				parsedURL.Path, parsedURL.RawPath = path, masked
+			}
+		}
+	}
+
+	if parsedURL.RawQuery != "" {
+		params := strings.Split(parsedURL.RawQuery, "&")
+		for i, param := range params {
+			key, _, _ := strings.Cut(param, "=")
+			name, err := url.QueryUnescape(key)
+			if err != nil {
+				name = key
+			}
+			if p.secretParam(name) {
+				params[i] = key + "=" + p.mask()
+			}
+		}
+		parsedURL.RawQuery = strings.Join(params, "&")
+	}
+
+	return parsedURL.String()
+}
+
+// Header - returns copy of headers with masked secret values
+func (p *RedactionPolicy) Header(h http.Header) http.Header {
+	res := h.Clone()
+	for _, name := range p.Headers {
+		if values := res.Values(name); len(values) > 0 {
+			masked := make([]string, len(values))
+			for i := range masked {
+				masked[i] = p.mask()
+			}
+			res[http.CanonicalHeaderKey(name)] = masked
+		}
+	}
+	return res
+}
+
+func (p *RedactionPolicy) secretParam(name string) bool {
+	for _, param := range p.Params {
+		if strings.EqualFold(param, name) {
+			return true
+		}
+	}
+	for _, re := range p.ParamPatterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskMatches - replaces capture groups of the pattern, or the whole match if pattern has no groups
+func (p *RedactionPolicy) maskMatches(re *regexp.Regexp, s string) string {
+	var b strings.Builder
+	last := 0
+	for _, match := range re.FindAllStringSubmatchIndex(s, -1) {
+		groups := match[2:]
+		if len(groups) == 0 {
+			groups = match[:2]
+		}
+		for i := 0; i < len(groups); i += 2 {
+			start, end := groups[i], groups[i+1]
+			if start < last || start == end {
+				continue
+			}
+			b.WriteString(s[last:start])
+			b.WriteString(p.mask())
+			last = end
+		}
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+func (p *RedactionPolicy) mask() string {
+	if p.Mask == "" {
+		return "***"
+	}
+	return p.Mask
+}
@@ -0,0 +1,139 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/require"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestRedactionPolicy_URL(t *testing.T) {
+	testCases := []struct {
+		name string
+		uri  string
+		want string
+	}{
+		{"no query", "/users/42", "/users/42"},
+		{"no secrets", "/users?page=2&limit=10", "/users?page=2&limit=10"},
+		{"jwt", "/test?jwt=eyJhbGciOiJSUzI1NiJ9.e30.sig", "/test?jwt=***"},
+		{"order kept", "/api?b=1&token=abc&a=2", "/api?b=1&token=***&a=2"},
+		{"case insensitive", "/api?API_KEY=abc", "/api?API_KEY=***"},
+		{"pattern", "/api?db_password=abc&x_refresh_token=def", "/api?db_password=***&x_refresh_token=***"},
+		{"signature", "/download?file=a.zip&signature=abc&X-Amz-Signature=def", "/download?file=a.zip&signature=***&X-Amz-Signature=def"},
+		{"repeated", "/api?token=a&token=b", "/api?token=***&token=***"},
+		{"escaped name", "/api?access%5Ftoken=abc", "/api?access%5Ftoken=***"},
+		{"empty value", "/api?token=", "/api?token=***"},
+		{"absolute", "https://example.com/api?secret=abc#top", "https://example.com/api?secret=***#top"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, DefaultRedactionPolicy.URL(tc.uri))
+		})
+	}
+
+	t.Run("path patterns", func(t *testing.T) {
+		p := &RedactionPolicy{
+			PathPatterns: []*regexp.Regexp{
+				regexp.MustCompile(`/reset/([^/]+)`),
+				regexp.MustCompile(`sk_live_[a-zA-Z0-9]+`),
+			},
+			Mask: "[redacted]",
+		}
+
+		require.Equal(t, "/reset/[redacted]/confirm", p.URL("/reset/abcdef/confirm"))
+		require.Equal(t, "/keys/[redacted]", p.URL("/keys/sk_live_abc123"))
+		require.Equal(t, "/users/42", p.URL("/users/42"))
+	})
+
+	t.Run("custom params", func(t *testing.T) {
+		p := &RedactionPolicy{Params: []string{"code"}}
+		require.Equal(t, "/callback?code=***&state=xyz&token=abc", p.URL("/callback?code=123&state=xyz&token=abc"))
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		require.Equal(t, "%zz", DefaultRedactionPolicy.URL("%zz"))
+	})
+}
+
+func TestRedactionPolicy_Header(t *testing.T) {
+	h := http.Header{}
+	h.Set("Authorization", "Bearer secret")
+	h.Add("Cookie", "session=1")
+	h.Add("Cookie", "theme=dark")
+	h.Set("Accept", "application/json")
+
+	masked := DefaultRedactionPolicy.Header(h)
+	require.Equal(t, "***", masked.Get("Authorization"))
+	require.Equal(t, []string{"***", "***"}, masked.Values("Cookie"))
+	require.Equal(t, "application/json", masked.Get("Accept"))
+	require.Equal(t, "Bearer secret", h.Get("Authorization"), "original headers must not be changed")
+}
+
+func TestRedaction_logs(t *testing.T) {
+	originalOutput := log.Writer()
+	defer log.SetOutput(originalOutput)
+
+	t.Run("Logger", func(t *testing.T) {
+		var buf bytes.Buffer
+		log.SetOutput(&buf)
+
+		handler := Logger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api?access_token=secret-value&page=1", nil))
+
+		require.Contains(t, buf.String(), "access_token=***")
+		require.Contains(t, buf.String(), "page=1")
+		require.NotContains(t, buf.String(), "secret-value")
+	})
+
+	t.Run("LoggerWithConfig", func(t *testing.T) {
+		var entry LogEntry
+		handler := LoggerWithConfig(LoggerConfig{
+			Headers: []string{"Authorization", "Accept", "X-Missing"},
+			Sink: LogSinkFunc(func(_ context.Context, e LogEntry) {
+				entry = e
+			}),
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		req := httptest.NewRequest("GET", "/api?api_key=secret-value", nil)
+		req.Header.Set("Authorization", "Bearer secret-value")
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Cookie", "session=secret-value")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		require.Equal(t, "/api?api_key=***", entry.Path)
+		require.Equal(t, http.Header{
+			"Authorization": {"***"},
+			"Accept":        {"application/json"},
+		}, entry.Headers)
+	})
+
+	t.Run("LoggerWithConfig sink headers", func(t *testing.T) {
+		var buf bytes.Buffer
+		handler := LoggerWithConfig(LoggerConfig{
+			Headers: []string{"Authorization"},
+			Sink:    JSONSink(&buf),
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer secret-value")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		require.Contains(t, buf.String(), `"headers":{"Authorization":"***"}`)
+	})
+
+	t.Run("ErrorResponse", func(t *testing.T) {
+		var buf bytes.Buffer
+		log.SetOutput(&buf)
+
+		req := httptest.NewRequest("GET", "/api?password=secret-value", nil)
+		ErrorResponse(httptest.NewRecorder(), req, http.StatusBadRequest, nil, "")
+
+		require.Contains(t, buf.String(), "password=***")
+		require.NotContains(t, buf.String(), "secret-value")
+	})
+}
@@ -0,0 +1,149 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// ErrorMapping - http representation of the error
+type ErrorMapping struct {
+	Status  int    // http status, 500 if zero
+	Code    string // public code rendered as HttpError.Err, upper snake case status text if empty
+	Message string // public message rendered as HttpError.Message
+}
+
+// ErrorRegistry - maps errors to the http status, public code and message.
+// Errors registered later take precedence, so the defaults can be overridden, including the ones of the package error types.
+type ErrorRegistry struct {
+	entries []registryEntry
+	mu      sync.RWMutex
+}
+
+type registryEntry struct {
+	match   func(err error) bool
+	mapping ErrorMapping
+	builtin bool // package default, StatusCoder and PublicError of the error take precedence over it
+}
+
+// DefaultErrorRegistry - registry used by Error, contains the package errors
+var DefaultErrorRegistry = NewErrorRegistry()
+
+// NewErrorRegistry - creates registry with the package errors
+func NewErrorRegistry() *ErrorRegistry {
+	reg := &ErrorRegistry{}
+	reg.Register(ErrEmptyRequest, ErrorMapping{Status: http.StatusBadRequest, Code: "EMPTY_REQUEST"})
+	reg.Register(ErrUnmarshal, ErrorMapping{Status: http.StatusBadRequest, Code: ErrUnmarshal.Error()})
+	reg.Register(ErrMissingField, ErrorMapping{Status: http.StatusBadRequest, Code: ErrMissingField.Error()})
+	reg.Register(ErrValidate, ErrorMapping{Status: http.StatusUnprocessableEntity, Code: ErrValidate.Error()})
+	reg.Register(ErrNotFound, ErrorMapping{Status: http.StatusNotFound, Code: ErrNotFound.Error()})
+	for i := range reg.entries {
+		reg.entries[i].builtin = true
+	}
+	return reg
+}
+
+// Register - maps errors matching target with errors.Is
+func (reg *ErrorRegistry) Register(target error, mapping ErrorMapping) {
+	reg.RegisterFunc(func(err error) bool {
+		return errors.Is(err, target)
+	}, mapping)
+}
+
+// RegisterFunc - maps errors for which match returns true
+func (reg *ErrorRegistry) RegisterFunc(match func(err error) bool, mapping ErrorMapping) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.entries = append(reg.entries, registryEntry{match: match, mapping: mapping})
+}
+
+// RegisterErrorType - maps errors having T in the chain, matched with errors.As
+func RegisterErrorType[T error](reg *ErrorRegistry, mapping ErrorMapping) {
+	reg.RegisterFunc(func(err error) bool {
+		var target T
+		return errors.As(err, &target)
+	}, mapping)
+}
+
+// Resolve - returns mapping of the error. Registered mappings take precedence, StatusCoder and PublicError
+// of the error fill only the fields left empty, and override the package defaults.
+// Unknown errors are mapped to 500 without message, false is returned for them.
+func (reg *ErrorRegistry) Resolve(err error) (ErrorMapping, bool) {
+	return reg.resolve(err, 0)
+}
+
+// resolve - returns mapping of the error, status is used by ErrorResponse for errors without own status,
+// it takes precedence over the package defaults only
+func (reg *ErrorRegistry) resolve(err error, status int) (ErrorMapping, bool) {
+	entry, found := reg.lookup(err)
+
+	var own ErrorMapping
+	var statusCoder StatusCoder
+	if errors.As(err, &statusCoder) {
+		own.Status = statusCoder.StatusCode()
+		found = true
+	}
+	var public PublicError
+	if errors.As(err, &public) {
+		own.Code, own.Message = public.PublicCode(), public.PublicMessage()
+		found = true
+	}
+
+	mapping, fallback := entry.mapping, own
+	statuses := []int{entry.mapping.Status, own.Status, status}
+	if entry.builtin {
+		mapping, fallback = own, entry.mapping
+		statuses = []int{own.Status, status, entry.mapping.Status}
+	}
+	for _, st := range statuses {
+		if st != 0 {
+			mapping.Status = st
+			break
+		}
+	}
+	if mapping.Code == "" {
+		mapping.Code = fallback.Code
+	}
+	if mapping.Message == "" {
+		mapping.Message = fallback.Message
+	}
+
+	if mapping.Status == 0 {
+		mapping.Status = http.StatusInternalServerError
+	}
+	if mapping.Code == "" {
+		mapping.Code = statusCode(mapping.Status)
+	}
+	return mapping, found
+}
+
+// Render - writes the error as HttpError with the resolved status, code and message.
+// Unknown errors are logged and rendered as 500 without the error text, unless debug mode is enabled.
+func (reg *ErrorRegistry) Render(w http.ResponseWriter, r *http.Request, err error) {
+	mapping, found := reg.Resolve(err)
+
+	httpErr := newHttpError(r, mapping, found, err, "")
+	logError(r, mapping.Status, err, httpErr)
+
+	renderError(w, r, mapping.Status, err, httpErr)
+}
+
+func (reg *ErrorRegistry) lookup(err error) (registryEntry, bool) {
+	if err == nil {
+		return registryEntry{}, false
+	}
+
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	for i := len(reg.entries) - 1; i >= 0; i-- {
+		if reg.entries[i].match(err) {
+			return reg.entries[i], true
+		}
+	}
+	return registryEntry{}, false
+}
+
+// Error - writes the error with the status, code and message from DefaultErrorRegistry
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	DefaultErrorRegistry.Render(w, r, err)
+}
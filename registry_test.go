@@ -0,0 +1,216 @@
+package rest
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type quotaError struct {
+	Limit int
+}
+
+func (e *quotaError) Error() string {
+	return fmt.Sprintf("quota of %d requests exceeded", e.Limit)
+}
+
+func TestErrorRegistry_Resolve(t *testing.T) {
+	errConflict := errors.New("conflict")
+
+	reg := NewErrorRegistry()
+	reg.Register(errConflict, ErrorMapping{Status: http.StatusConflict, Message: "already exists"})
+	RegisterErrorType[*quotaError](reg, ErrorMapping{Status: http.StatusTooManyRequests, Code: "QUOTA_EXCEEDED"})
+	reg.RegisterFunc(func(err error) bool {
+		return strings.HasPrefix(err.Error(), "teapot")
+	}, ErrorMapping{Status: http.StatusTeapot})
+
+	testCases := []struct {
+		name  string
+		err   error
+		want  ErrorMapping
+		found bool
+	}{
+		{name: "not found", err: ErrNotFound, want: ErrorMapping{Status: http.StatusNotFound, Code: "NOT_FOUND"}, found: true},
+		{name: "wrapped", err: fmt.Errorf("get user 42, %w", ErrNotFound), want: ErrorMapping{Status: http.StatusNotFound, Code: "NOT_FOUND"}, found: true},
+		{name: "unmarshal", err: ErrUnmarshal, want: ErrorMapping{Status: http.StatusBadRequest, Code: "UNMARSHAL_ERROR"}, found: true},
+		{name: "missing field", err: ErrMissingField, want: ErrorMapping{Status: http.StatusBadRequest, Code: "MISSING_FIELD"}, found: true},
+		{name: "validate", err: ErrValidate, want: ErrorMapping{Status: http.StatusUnprocessableEntity, Code: "VALIDATION_ERROR"}, found: true},
+		{name: "empty request", err: ErrEmptyRequest, want: ErrorMapping{Status: http.StatusBadRequest, Code: "EMPTY_REQUEST"}, found: true},
+		{
+			name:  "body too large",
+			err:   &BodyTooLargeError{Limit: 10},
+			want:  ErrorMapping{Status: http.StatusRequestEntityTooLarge, Code: "REQUEST_ENTITY_TOO_LARGE", Message: "request body is larger than 10 bytes"},
+			found: true,
+		},
+		{
+			name:  "validation error",
+			err:   &ValidationError{Fields: []FieldError{{Field: "name", Rule: "required", Message: "is required"}}},
+			want:  ErrorMapping{Status: http.StatusUnprocessableEntity, Code: "VALIDATION_ERROR", Message: "validation failed: name is required"},
+			found: true,
+		},
+		{name: "registered", err: fmt.Errorf("insert, %w", errConflict), want: ErrorMapping{Status: http.StatusConflict, Code: "CONFLICT", Message: "already exists"}, found: true},
+		{name: "registered type", err: fmt.Errorf("call, %w", &quotaError{Limit: 5}), want: ErrorMapping{Status: http.StatusTooManyRequests, Code: "QUOTA_EXCEEDED"}, found: true},
+		{name: "registered func", err: errors.New("teapot is empty"), want: ErrorMapping{Status: http.StatusTeapot, Code: "I'M_A_TEAPOT"}, found: true},
+		{name: "unknown", err: sql.ErrConnDone, want: ErrorMapping{Status: http.StatusInternalServerError, Code: "INTERNAL_SERVER_ERROR"}},
+		{name: "nil", err: nil, want: ErrorMapping{Status: http.StatusInternalServerError, Code: "INTERNAL_SERVER_ERROR"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mapping, found := reg.Resolve(tc.err)
+			require.Equal(t, tc.want, mapping)
+			require.Equal(t, tc.found, found)
+		})
+	}
+
+	t.Run("override", func(t *testing.T) {
+		reg := NewErrorRegistry()
+		reg.Register(ErrNotFound, ErrorMapping{Status: http.StatusGone, Code: "GONE"})
+
+		mapping, found := reg.Resolve(ErrNotFound)
+		require.True(t, found)
+		require.Equal(t, ErrorMapping{Status: http.StatusGone, Code: "GONE"}, mapping)
+
+		mapping, _ = NewErrorRegistry().Resolve(ErrNotFound)
+		require.Equal(t, http.StatusNotFound, mapping.Status)
+	})
+
+	t.Run("override package error types", func(t *testing.T) {
+		reg := NewErrorRegistry()
+		reg.Register(ErrValidate, ErrorMapping{Status: http.StatusBadRequest, Code: "BAD_INPUT", Message: "bad input"})
+		reg.Register(ErrUnmarshal, ErrorMapping{Status: http.StatusUnprocessableEntity})
+
+		mapping, found := reg.Resolve(&ValidationError{Fields: []FieldError{{Field: "name", Rule: "required", Message: "is required"}}})
+		require.True(t, found)
+		require.Equal(t, ErrorMapping{Status: http.StatusBadRequest, Code: "BAD_INPUT", Message: "bad input"}, mapping)
+
+		mapping, found = reg.Resolve(&SyntaxError{Offset: 3, Err: errors.New("unexpected end")})
+		require.True(t, found)
+		require.Equal(t, http.StatusUnprocessableEntity, mapping.Status)
+		require.Equal(t, "UNMARSHAL_ERROR", mapping.Code, "code of the error fills the empty field")
+	})
+}
+
+func TestError(t *testing.T) {
+	originalOutput := log.Writer()
+	defer log.SetOutput(originalOutput)
+
+	render := func(err error) (*httptest.ResponseRecorder, HttpError) {
+		req := httptest.NewRequest("GET", "/users/42?token=secret", nil)
+		req.Header.Set("Uber-Trace-Id", "trace-1")
+		w := httptest.NewRecorder()
+		Error(w, req, err)
+
+		var response HttpError
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w, response
+	}
+
+	t.Run("known error", func(t *testing.T) {
+		log.SetOutput(io.Discard)
+
+		w, response := render(fmt.Errorf("user 42, %w", ErrNotFound))
+		require.Equal(t, http.StatusNotFound, w.Code)
+		require.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+		require.Equal(t, HttpError{Err: "NOT_FOUND", TraceID: "trace-1"}, response)
+	})
+
+	t.Run("validation error", func(t *testing.T) {
+		log.SetOutput(io.Discard)
+
+		w, response := render(&ValidationError{Fields: []FieldError{{Field: "name", Rule: "required", Message: "is required"}}})
+		require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		require.Equal(t, "VALIDATION_ERROR", response.Err)
+		require.Equal(t, []FieldError{{Field: "name", Rule: "required", Message: "is required"}}, response.Details)
+	})
+
+	t.Run("internal error", func(t *testing.T) {
+		var buf bytes.Buffer
+		log.SetOutput(&buf)
+
+		w, response := render(errors.New(`pq: password authentication failed for user "admin"`))
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		require.Equal(t, HttpError{Err: "INTERNAL_SERVER_ERROR", TraceID: "trace-1"}, response)
+		require.NotContains(t, w.Body.String(), "password")
+
+		require.Contains(t, buf.String(), `[ERROR] GET - /users/42?token=*** - 500 (Internal Server Error) - INTERNAL_SERVER_ERROR -  - pq: password authentication failed for user "admin"`)
+	})
+
+	t.Run("debug", func(t *testing.T) {
+		log.SetOutput(io.Discard)
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req = req.WithContext(ContextWithDebug(req.Context()))
+		w := httptest.NewRecorder()
+		Error(w, req, errors.New("connection refused"))
+
+		var response HttpError
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Equal(t, HttpError{Err: "INTERNAL_SERVER_ERROR", Debug: "connection refused"}, response)
+
+		w = httptest.NewRecorder()
+		Error(w, req, ErrNotFound)
+		require.NotContains(t, w.Body.String(), "debug")
+	})
+
+	t.Run("same as ErrorResponse", func(t *testing.T) {
+		log.SetOutput(io.Discard)
+		defaultRegistry := DefaultErrorRegistry
+		DefaultErrorRegistry = NewErrorRegistry()
+		defer func() { DefaultErrorRegistry = defaultRegistry }()
+		DefaultErrorRegistry.Register(ErrValidate, ErrorMapping{Status: http.StatusBadRequest, Code: "BAD_INPUT"})
+
+		testCases := []struct {
+			name   string
+			err    error
+			status int
+			code   string
+		}{
+			{"overridden type", &ValidationError{Fields: []FieldError{{Field: "name", Rule: "required", Message: "is required"}}}, http.StatusBadRequest, "BAD_INPUT"},
+			{"public error", HttpError{Err: "MY", Message: "my error"}, http.StatusInternalServerError, "MY"},
+			{"package type", &BodyTooLargeError{Limit: 10}, http.StatusRequestEntityTooLarge, "REQUEST_ENTITY_TOO_LARGE"},
+			{"unknown", errors.New("connection refused"), http.StatusInternalServerError, "INTERNAL_SERVER_ERROR"},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				errorW, response := render(tc.err)
+				require.Equal(t, tc.status, errorW.Code)
+				require.Equal(t, tc.code, response.Err)
+
+				responseW := httptest.NewRecorder()
+				req := httptest.NewRequest("GET", "/users/42?token=secret", nil)
+				req.Header.Set("Uber-Trace-Id", "trace-1")
+				ErrorResponse(responseW, req, http.StatusInternalServerError, tc.err, "")
+				require.Equal(t, errorW.Code, responseW.Code)
+				require.Equal(t, errorW.Body.String(), responseW.Body.String())
+			})
+		}
+	})
+
+	t.Run("problem details", func(t *testing.T) {
+		log.SetOutput(io.Discard)
+		ProblemDetails = true
+		defer func() { ProblemDetails = false }()
+
+		req := httptest.NewRequest("GET", "/users/42", nil)
+		w := httptest.NewRecorder()
+		Error(w, req, ErrNotFound)
+
+		require.Equal(t, http.StatusNotFound, w.Code)
+		require.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+		var p Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+		require.Equal(t, "urn:problem-type:not-found", p.Type)
+		require.Equal(t, "NOT_FOUND", p.Extensions["code"])
+	})
+}
@@ -0,0 +1,185 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Encoder - writes data in the media type of the encoder
+type Encoder func(w io.Writer, data interface{}) error
+
+// EncoderRegistry - encoders keyed by media type used by Render for content negotiation.
+// Encoders registered first are preferred when the client accepts several types with the same quality.
+type EncoderRegistry struct {
+	encoders []mediaEncoder
+	mu       sync.RWMutex
+}
+
+type mediaEncoder struct {
+	mediaType   string
+	contentType string
+	encode      Encoder
+}
+
+// DefaultEncoders - registry used by Render, contains JSON and XML encoders
+var DefaultEncoders = NewEncoderRegistry()
+
+// NewEncoderRegistry - creates registry with JSON (default) and XML encoders
+func NewEncoderRegistry() *EncoderRegistry {
+	reg := &EncoderRegistry{}
+	reg.Register("application/json; charset=utf-8", encodeJSON)
+	reg.Register("application/xml; charset=utf-8", encodeXML)
+	reg.Register("text/xml; charset=utf-8", encodeXML)
+	return reg
+}
+
+// Register - adds or replaces the encoder of the content type, like application/msgpack
+func (reg *EncoderRegistry) Register(contentType string, encoder Encoder) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	for i, e := range reg.encoders {
+		if e.mediaType == mediaType {
+			reg.encoders[i] = mediaEncoder{mediaType: mediaType, contentType: contentType, encode: encoder}
+			return
+		}
+	}
+	reg.encoders = append(reg.encoders, mediaEncoder{mediaType: mediaType, contentType: contentType, encode: encoder})
+}
+
+// Render - sends data encoded in the media type preferred by the Accept header, responds 406 if no encoder is acceptable
+func (reg *EncoderRegistry) Render(w http.ResponseWriter, r *http.Request, code int, data interface{}) {
+	addVary(w.Header(), "Accept")
+
+	encoder, ok := reg.negotiate(r.Header.Values("Accept"))
+	if !ok {
+		ErrorResponse(w, r, http.StatusNotAcceptable, nil, "")
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	if data != nil {
+		if err := encoder.encode(buf, data); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", encoder.contentType)
+	w.WriteHeader(code)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// Render - sends data in the format negotiated by the Accept header with DefaultEncoders
+func Render(w http.ResponseWriter, r *http.Request, code int, data interface{}) {
+	DefaultEncoders.Render(w, r, code, data)
+}
+
+// negotiate - returns the encoder with the highest quality in the Accept header, the first encoder if the header is empty
+func (reg *EncoderRegistry) negotiate(accept []string) (mediaEncoder, bool) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		if len(reg.encoders) == 0 {
+			return mediaEncoder{}, false
+		}
+		return reg.encoders[0], true
+	}
+
+	var best mediaEncoder
+	var bestQ float64
+	for _, e := range reg.encoders {
+		if q := acceptQuality(ranges, e.mediaType); q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// parseAccept - parses media ranges with quality values of the Accept headers
+func parseAccept(values []string) []acceptRange {
+	var res []acceptRange
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			if strings.TrimSpace(part) == "" {
+				continue
+			}
+			mediaType, params, err := mime.ParseMediaType(part)
+			if err != nil {
+				continue
+			}
+			q := 1.0
+			if v, ok := params["q"]; ok {
+				if q, err = strconv.ParseFloat(v, 64); err != nil || q < 0 || q > 1 {
+					continue
+				}
+			}
+			res = append(res, acceptRange{mediaType: mediaType, q: q})
+		}
+	}
+	return res
+}
+
+// acceptQuality - returns quality of the most specific range matching the media type
+func acceptQuality(ranges []acceptRange, mediaType string) float64 {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.mediaType == mediaType:
+			s = 2
+		case r.mediaType == typ+"/*":
+			s = 1
+		case r.mediaType == "*/*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
+
+// addVary - adds the header name to Vary if it is not there yet
+func addVary(h http.Header, name string) {
+	for _, v := range h.Values("Vary") {
+		for _, field := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), name) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", name)
+}
+
+func encodeJSON(w io.Writer, data interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(true)
+	return enc.Encode(data)
+}
+
+func encodeXML(w io.Writer, data interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(data)
+}
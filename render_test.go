@@ -0,0 +1,141 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type renderItem struct {
+	ID   int    `json:"id" xml:"id,attr"`
+	Name string `json:"name" xml:"name"`
+}
+
+func TestRender(t *testing.T) {
+	item := renderItem{ID: 1, Name: "book"}
+
+	render := func(reg *EncoderRegistry, accept ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		for _, v := range accept {
+			req.Header.Add("Accept", v)
+		}
+		w := httptest.NewRecorder()
+		if reg == nil {
+			Render(w, req, http.StatusCreated, item)
+		} else {
+			reg.Render(w, req, http.StatusCreated, item)
+		}
+		return w
+	}
+
+	t.Run("json by default", func(t *testing.T) {
+		w := render(nil)
+		require.Equal(t, http.StatusCreated, w.Code)
+		require.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+		require.Equal(t, "Accept", w.Header().Get("Vary"))
+		require.Equal(t, `{"id":1,"name":"book"}`+"\n", w.Body.String())
+	})
+
+	t.Run("xml", func(t *testing.T) {
+		w := render(nil, "application/xml")
+		require.Equal(t, http.StatusCreated, w.Code)
+		require.Equal(t, "application/xml; charset=utf-8", w.Header().Get("Content-Type"))
+		require.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<renderItem id="1"><name>book</name></renderItem>`, w.Body.String())
+
+		w = render(nil, "text/xml")
+		require.Equal(t, "text/xml; charset=utf-8", w.Header().Get("Content-Type"))
+	})
+
+	testCases := []struct {
+		name   string
+		accept []string
+		want   string
+	}{
+		{name: "any", accept: []string{"*/*"}, want: "application/json; charset=utf-8"},
+		{name: "quality", accept: []string{"application/json;q=0.5, application/xml;q=0.9"}, want: "application/xml; charset=utf-8"},
+		{name: "server preference on tie", accept: []string{"application/xml, application/json"}, want: "application/json; charset=utf-8"},
+		{name: "wildcard subtype", accept: []string{"text/*"}, want: "text/xml; charset=utf-8"},
+		{name: "specific range wins", accept: []string{"*/*;q=0.8, application/json;q=0.1"}, want: "application/xml; charset=utf-8"},
+		{name: "multiple headers", accept: []string{"text/html", "application/xml"}, want: "application/xml; charset=utf-8"},
+		{name: "browser", accept: []string{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"}, want: "application/xml; charset=utf-8"},
+		{name: "invalid ranges skipped", accept: []string{"garbage;;, application/xml;q=2, application/json;q=0.3"}, want: "application/json; charset=utf-8"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := render(nil, tc.accept...)
+			require.Equal(t, http.StatusCreated, w.Code)
+			require.Equal(t, tc.want, w.Header().Get("Content-Type"))
+		})
+	}
+
+	t.Run("not acceptable", func(t *testing.T) {
+		originalOutput := log.Writer()
+		log.SetOutput(io.Discard)
+		defer log.SetOutput(originalOutput)
+
+		for _, accept := range []string{"text/html", "application/json;q=0, */*;q=0"} {
+			w := render(nil, accept)
+			require.Equal(t, http.StatusNotAcceptable, w.Code)
+			require.Equal(t, "Accept", w.Header().Get("Vary"))
+
+			var response HttpError
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			require.Equal(t, "NOT_ACCEPTABLE", response.Err)
+		}
+	})
+
+	t.Run("custom encoder", func(t *testing.T) {
+		reg := NewEncoderRegistry()
+		reg.Register("application/msgpack", func(w io.Writer, data interface{}) error {
+			_, err := fmt.Fprintf(w, "msgpack:%v", data)
+			return err
+		})
+
+		w := render(reg, "application/msgpack, application/json;q=0.5")
+		require.Equal(t, "application/msgpack", w.Header().Get("Content-Type"))
+		require.Equal(t, "msgpack:{1 book}", w.Body.String())
+
+		w = render(reg)
+		require.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	})
+
+	t.Run("replace encoder", func(t *testing.T) {
+		reg := NewEncoderRegistry()
+		reg.Register("application/json", func(w io.Writer, data interface{}) error {
+			_, err := io.WriteString(w, "custom")
+			return err
+		})
+
+		w := render(reg)
+		require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		require.Equal(t, "custom", w.Body.String())
+	})
+
+	t.Run("encoding error", func(t *testing.T) {
+		reg := NewEncoderRegistry()
+		reg.Register("application/json", func(w io.Writer, data interface{}) error {
+			return errors.New("encoding failed")
+		})
+
+		w := render(reg)
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		require.Equal(t, "encoding failed\n", w.Body.String())
+	})
+
+	t.Run("vary is not duplicated", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		w := httptest.NewRecorder()
+		w.Header().Set("Vary", "Accept-Encoding, accept")
+		Render(w, req, http.StatusOK, nil)
+
+		require.Equal(t, []string{"Accept-Encoding, accept"}, w.Header().Values("Vary"))
+		require.Empty(t, w.Body.String())
+	})
+}
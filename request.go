@@ -1,10 +1,13 @@
 package rest
 
 import (
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"reflect"
 	"strings"
 )
@@ -12,7 +15,7 @@ import (
 var ErrEmptyRequest = errors.New("empty request")
 var ErrNotPointer = errors.New("not pointer provided")
 
-// ReadBody - read body from request and trying to unmarshal to provided struct
+// ReadBody - read body from request, trying to unmarshal to provided struct and validate it by the validate tags
 func ReadBody(r *http.Request, str interface{}) error {
 	if r == nil {
 		return ErrEmptyRequest
@@ -31,11 +34,15 @@ func ReadBody(r *http.Request, str interface{}) error {
 		return err
 	}
 
-	return nil
+	return Validate(str)
 }
 
-// GetAddr - get client address from request
+// GetAddr - get client address from request, the address resolved by ClientIPResolver middleware is used if present
 func GetAddr(r *http.Request) string {
+	if ip, ok := GetClientIP(r.Context()); ok {
+		return ip.String()
+	}
+
 	addr := r.RemoteAddr
 	if CFAddr := r.Header.Get("CF-Connecting-IP"); CFAddr != "" {
 		addr = CFAddr
@@ -61,3 +68,33 @@ func GetAddr(r *http.Request) string {
 
 	return addr
 }
+
+// PeerCertificate - verified client certificate of the tls connection
+type PeerCertificate struct {
+	Subject        string
+	CommonName     string
+	DNSNames       []string
+	EmailAddresses []string
+	IPAddresses    []net.IP
+	URIs           []*url.URL
+
+	Certificate *x509.Certificate
+}
+
+// GetPeerCertificate - get verified client certificate from request, returns false if the client is not authenticated by certificate
+func GetPeerCertificate(r *http.Request) (*PeerCertificate, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+
+	cert := r.TLS.VerifiedChains[0][0]
+	return &PeerCertificate{
+		Subject:        cert.Subject.String(),
+		CommonName:     cert.Subject.CommonName,
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		IPAddresses:    cert.IPAddresses,
+		URIs:           cert.URIs,
+		Certificate:    cert,
+	}, true
+}
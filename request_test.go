@@ -2,9 +2,13 @@ package rest
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
 	"errors"
 	"github.com/stretchr/testify/require"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -178,3 +182,43 @@ func TestGetAddr(t *testing.T) {
 		require.Equal(t, "[::1]", addr)
 	})
 }
+
+func TestGetPeerCertificate(t *testing.T) {
+	t.Run("plain http", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/test", nil)
+
+		cert, ok := GetPeerCertificate(req)
+		require.False(t, ok)
+		require.Nil(t, cert)
+	})
+
+	t.Run("tls without verified chains", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.TLS = &tls.ConnectionState{}
+
+		_, ok := GetPeerCertificate(req)
+		require.False(t, ok)
+	})
+
+	t.Run("verified certificate", func(t *testing.T) {
+		leaf := &x509.Certificate{
+			Subject:        pkix.Name{CommonName: "service-a", Organization: []string{"pkgz"}},
+			DNSNames:       []string{"service-a.internal"},
+			EmailAddresses: []string{"ops@example.com"},
+			IPAddresses:    []net.IP{net.ParseIP("10.0.0.1")},
+		}
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.TLS = &tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{leaf}},
+		}
+
+		cert, ok := GetPeerCertificate(req)
+		require.True(t, ok)
+		require.Equal(t, "CN=service-a,O=pkgz", cert.Subject)
+		require.Equal(t, "service-a", cert.CommonName)
+		require.Equal(t, []string{"service-a.internal"}, cert.DNSNames)
+		require.Equal(t, []string{"ops@example.com"}, cert.EmailAddresses)
+		require.Equal(t, "10.0.0.1", cert.IPAddresses[0].String())
+		require.Equal(t, leaf, cert.Certificate)
+	})
+}
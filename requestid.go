@@ -0,0 +1,71 @@
+package rest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/go-chi/chi/v5/middleware"
+	"net/http"
+)
+
+// RequestIDHeader - default header with the request id
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength - longer incoming request ids are replaced by generated ones
+const maxRequestIDLength = 128
+
+type contextKey struct {
+	name string
+}
+
+var requestIDKey = &contextKey{"request-id"}
+
+// RequestID - middleware which takes the request id from the header or generates a new one,
+// stores it in the request context and echoes it in the response header. X-Request-ID is used if header is empty.
+func RequestID(header string) func(http.Handler) http.Handler {
+	if header == "" {
+		header = RequestIDHeader
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(header)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+
+			ctx := context.WithValue(r.Context(), requestIDKey, id)
+			ctx = context.WithValue(ctx, middleware.RequestIDKey, id)
+			w.Header().Set(header, id)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetRequestID - returns the request id from the context, set by RequestID or chi RequestID middleware
+func GetRequestID(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey).(string); ok {
+		return id
+	}
+	return middleware.GetReqID(ctx)
+}
+
+// validRequestID - accepts non-empty ids of printable ascii characters to keep logs and headers safe
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
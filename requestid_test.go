@@ -0,0 +1,128 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/require"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	var requestID, chiRequestID string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		requestID = GetRequestID(r.Context())
+		chiRequestID = middleware.GetReqID(r.Context())
+	}
+
+	t.Run("generated", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		RequestID("")(http.HandlerFunc(handler)).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+		require.Len(t, requestID, 32)
+		require.Equal(t, requestID, chiRequestID)
+		require.Equal(t, requestID, w.Header().Get("X-Request-ID"))
+	})
+
+	t.Run("unique", func(t *testing.T) {
+		ids := map[string]bool{}
+		for i := 0; i < 100; i++ {
+			RequestID("")(http.HandlerFunc(handler)).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+			ids[requestID] = true
+		}
+		require.Len(t, ids, 100)
+	})
+
+	t.Run("incoming", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Request-ID", "abc-123")
+		w := httptest.NewRecorder()
+		RequestID("")(http.HandlerFunc(handler)).ServeHTTP(w, req)
+
+		require.Equal(t, "abc-123", requestID)
+		require.Equal(t, "abc-123", w.Header().Get("X-Request-ID"))
+	})
+
+	t.Run("custom header", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Correlation-ID", "correlation")
+		req.Header.Set("X-Request-ID", "ignored")
+		w := httptest.NewRecorder()
+		RequestID("X-Correlation-ID")(http.HandlerFunc(handler)).ServeHTTP(w, req)
+
+		require.Equal(t, "correlation", requestID)
+		require.Equal(t, "correlation", w.Header().Get("X-Correlation-ID"))
+		require.Empty(t, w.Header().Get("X-Request-ID"))
+	})
+
+	t.Run("invalid incoming", func(t *testing.T) {
+		for _, id := range []string{"with space", "line\nbreak", strings.Repeat("a", 129), "юникод"} {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-Request-ID", id)
+			RequestID("")(http.HandlerFunc(handler)).ServeHTTP(httptest.NewRecorder(), req)
+
+			require.NotEqual(t, id, requestID)
+			require.Len(t, requestID, 32)
+		}
+	})
+
+	t.Run("chi middleware", func(t *testing.T) {
+		middleware.RequestID(http.HandlerFunc(handler)).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		require.NotEmpty(t, requestID)
+		require.Equal(t, chiRequestID, requestID)
+	})
+
+	t.Run("without middleware", func(t *testing.T) {
+		http.HandlerFunc(handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		require.Empty(t, requestID)
+	})
+}
+
+func TestRequestID_propagation(t *testing.T) {
+	originalOutput := log.Writer()
+	defer log.SetOutput(originalOutput)
+
+	t.Run("logger", func(t *testing.T) {
+		var buf bytes.Buffer
+		log.SetOutput(&buf)
+
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-Request-ID", "req-42")
+		RequestID("")(Logger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))).ServeHTTP(httptest.NewRecorder(), req)
+
+		require.Contains(t, buf.String(), "[DEBUG] GET - /test - 192.0.2.1 - 200 - ")
+		require.True(t, strings.HasSuffix(strings.TrimSpace(buf.String()), " - req-42"))
+	})
+
+	t.Run("error response", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Request-ID", "req-42")
+		w := httptest.NewRecorder()
+		RequestID("")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ErrorResponse(w, r, http.StatusBadRequest, nil, "")
+		})).ServeHTTP(w, req)
+
+		var response HttpError
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Equal(t, "req-42", response.TraceID)
+		require.Equal(t, "req-42", w.Header().Get("X-Request-ID"))
+	})
+
+	t.Run("tracing header wins", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Request-ID", "req-42")
+		req.Header.Set("Uber-Trace-Id", "trace-42")
+		w := httptest.NewRecorder()
+		RequestID("")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ErrorResponse(w, r, http.StatusBadRequest, nil, "")
+		})).ServeHTTP(w, req)
+
+		var response HttpError
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Equal(t, "trace-42", response.TraceID)
+	})
+}
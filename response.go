@@ -2,11 +2,11 @@ package rest
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 	"net/http"
-	"net/url"
 	"strings"
 )
 
@@ -15,6 +15,10 @@ type HttpError struct {
 	Err     string `json:"error"`
 	Message string `json:"message,omitempty"`
 	TraceID string `json:"trace_id,omitempty"`
+	Stack   string `json:"stack,omitempty"`
+	Debug   string `json:"debug,omitempty"` // text of the internal error, only in debug mode
+
+	Details []FieldError `json:"details,omitempty"`
 }
 
 var (
@@ -24,11 +28,46 @@ var (
 	ErrValidate     = errors.New("VALIDATION_ERROR")
 )
 
+// StatusCoder - error with its own http status, ErrorResponse uses it instead of the passed code
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// PublicError - error which code and message are safe to show to clients.
+// Other errors are rendered with the generic status text and their text is only logged.
+type PublicError interface {
+	error
+	PublicCode() string    // stable code rendered as HttpError.Err, status text is used if empty
+	PublicMessage() string // message rendered as HttpError.Message if no message is passed
+}
+
+var debugKey = &contextKey{"debug"}
+
+// ContextWithDebug - returns a copy of ctx where error responses include the text of internal errors, use it only in development
+func ContextWithDebug(ctx context.Context) context.Context {
+	return context.WithValue(ctx, debugKey, true)
+}
+
+func isDebug(ctx context.Context) bool {
+	debug, _ := ctx.Value(debugKey).(bool)
+	return debug
+}
+
 // Just to confirm Error interface
 func (e HttpError) Error() string {
 	return e.Err
 }
 
+// PublicCode - returns Err, HttpError is a PublicError
+func (e HttpError) PublicCode() string {
+	return e.Err
+}
+
+// PublicMessage - returns Message
+func (e HttpError) PublicMessage() string {
+	return e.Message
+}
+
 // RenderJSON sends data as json
 func RenderJSON(w http.ResponseWriter, code int, data interface{}) {
 	buf := &bytes.Buffer{}
@@ -70,28 +109,74 @@ func OkResponse(w http.ResponseWriter) {
 	})
 }
 
-// ErrorResponse - write a HttpError structure as response
+// ErrorResponse - write a HttpError structure as response, or problem details if ProblemDetails is enabled
 func ErrorResponse(w http.ResponseWriter, r *http.Request, code int, error error, msg string) {
-	err := HttpError{
-		Err:     http.StatusText(code),
-		Message: msg,
-		TraceID: r.Header.Get("Uber-Trace-Id"),
+	if ProblemDetails {
+		ProblemResponse(w, r, code, error, msg)
+		return
 	}
 
+	code, err := resolveHttpError(r, code, error, msg)
+	logError(r, code, error, err)
+
+	RenderJSON(w, code, err)
+}
+
+// renderError - sends the prepared HttpError in the configured format
+func renderError(w http.ResponseWriter, r *http.Request, code int, error error, err HttpError) {
+	if ProblemDetails {
+		RenderProblem(w, newProblem(r, code, error, err))
+		return
+	}
+	RenderJSON(w, code, err)
+}
+
+// resolveHttpError - resolves the error with DefaultErrorRegistry, the same way as Error does.
+// code is the status of errors without own or registered status, msg overrides the resolved message.
+func resolveHttpError(r *http.Request, code int, error error, msg string) (int, HttpError) {
+	mapping, found := DefaultErrorRegistry.resolve(error, code)
+	return mapping.Status, newHttpError(r, mapping, found, error, msg)
+}
+
+// logError - logs the response with the full text of the error, server errors are logged with ERROR level
+func logError(r *http.Request, code int, error error, err HttpError) {
+	level := "DEBUG"
+	if code >= http.StatusInternalServerError {
+		level = "ERROR"
+	}
+	var cause string
 	if error != nil {
-		err.Err = error.Error()
+		cause = " - " + error.Error()
+	}
+	log.Printf("[%s] %s - %s - %d (%s) - %s - %s%s", level, r.Method, requestURI(r, DefaultRedactionPolicy), code, http.StatusText(code), err, err.Message, cause)
+}
+
+// newHttpError - builds HttpError with the request trace id from the resolved mapping of the error.
+// Text of unknown errors is exposed only in debug mode.
+func newHttpError(r *http.Request, mapping ErrorMapping, found bool, error error, msg string) HttpError {
+	err := HttpError{
+		Err:     mapping.Code,
+		Message: mapping.Message,
+		TraceID: traceID(r),
+	}
+	if msg != "" {
+		err.Message = msg
 	}
-	err.Err = strings.ToUpper(err.Err)
-	err.Err = strings.Replace(err.Err, " ", "_", -1)
 
-	uri := r.URL.String()
-	if qun, e := url.QueryUnescape(uri); e == nil {
-		uri = qun
+	var validationErr *ValidationError
+	if errors.As(error, &validationErr) {
+		err.Details = validationErr.Fields
 	}
 
-	log.Printf("[DEBUG] %s - %s - %d (%s) - %s - %s", r.Method, uri, code, http.StatusText(code), err, msg)
+	if !found && error != nil && isDebug(r.Context()) {
+		err.Debug = error.Error()
+	}
+	return err
+}
 
-	RenderJSON(w, code, err)
+// statusCode - returns the status text in upper snake case
+func statusCode(code int) string {
+	return strings.ReplaceAll(strings.ToUpper(http.StatusText(code)), " ", "_")
 }
 
 // NotFound - return error page for not found
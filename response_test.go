@@ -1,11 +1,14 @@
 package rest
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/stretchr/testify/require"
 	"io"
+	"log"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -49,8 +52,9 @@ func TestJsonError(t *testing.T) {
 		var response HttpError
 		require.NoError(t, json.Unmarshal(body, &response))
 
-		require.Equal(t, "ERR_TEST", response.Err)
+		require.Equal(t, "UNAUTHORIZED", response.Err)
 		require.Equal(t, "test", response.Message)
+		require.Empty(t, response.Debug)
 	})
 
 	t.Run("empty error", func(t *testing.T) {
@@ -91,6 +95,95 @@ func TestJsonError(t *testing.T) {
 	})
 }
 
+func TestErrorResponse_public(t *testing.T) {
+	originalOutput := log.Writer()
+	defer log.SetOutput(originalOutput)
+
+	render := func(r *http.Request, code int, err error, msg string) HttpError {
+		w := httptest.NewRecorder()
+		ErrorResponse(w, r, code, err, msg)
+
+		var response HttpError
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	t.Run("internal error", func(t *testing.T) {
+		var buf bytes.Buffer
+		log.SetOutput(&buf)
+
+		response := render(httptest.NewRequest("GET", "/", nil), http.StatusInternalServerError, errors.New("dial tcp 10.0.0.5:5432: connection refused"), "")
+		require.Equal(t, HttpError{Err: "INTERNAL_SERVER_ERROR"}, response)
+		require.Contains(t, buf.String(), "[ERROR] GET - / - 500 (Internal Server Error) - INTERNAL_SERVER_ERROR -  - dial tcp 10.0.0.5:5432: connection refused")
+	})
+
+	t.Run("public error", func(t *testing.T) {
+		log.SetOutput(io.Discard)
+
+		err := fmt.Errorf("create user, %w", HttpError{Err: "USER_EXISTS", Message: "user already exists"})
+		response := render(httptest.NewRequest("GET", "/", nil), http.StatusConflict, err, "")
+		require.Equal(t, "USER_EXISTS", response.Err)
+		require.Equal(t, "user already exists", response.Message)
+
+		response = render(httptest.NewRequest("GET", "/", nil), http.StatusConflict, err, "custom message")
+		require.Equal(t, "custom message", response.Message)
+	})
+
+	t.Run("registered error", func(t *testing.T) {
+		log.SetOutput(io.Discard)
+
+		response := render(httptest.NewRequest("GET", "/", nil), http.StatusNotFound, fmt.Errorf("user 42, %w", ErrNotFound), "")
+		require.Equal(t, "NOT_FOUND", response.Err)
+		require.Empty(t, response.Debug)
+	})
+
+	t.Run("debug", func(t *testing.T) {
+		log.SetOutput(io.Discard)
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req = req.WithContext(ContextWithDebug(req.Context()))
+		response := render(req, http.StatusInternalServerError, errors.New("connection refused"), "")
+		require.Equal(t, "INTERNAL_SERVER_ERROR", response.Err)
+		require.Equal(t, "connection refused", response.Debug)
+
+		response = render(req, http.StatusNotFound, ErrNotFound, "")
+		require.Empty(t, response.Debug)
+	})
+
+	t.Run("server debug", func(t *testing.T) {
+		log.SetOutput(io.Discard)
+
+		for _, debug := range []bool{true, false} {
+			srv := &Server{Port: freePort(t), Debug: debug}
+			ctx, cancel := context.WithCancel(context.Background())
+			router := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ErrorResponse(w, r, http.StatusInternalServerError, errors.New("connection refused"), "")
+			})
+
+			done := make(chan error)
+			go func() {
+				done <- srv.RunContext(ctx, router)
+			}()
+			waitForServer(t, fmt.Sprintf("localhost:%d", srv.Port))
+
+			resp, err := http.Get(fmt.Sprintf("http://localhost:%d/", srv.Port))
+			require.NoError(t, err)
+			var response HttpError
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+			_ = resp.Body.Close()
+
+			if debug {
+				require.Equal(t, "connection refused", response.Debug)
+			} else {
+				require.Empty(t, response.Debug)
+			}
+
+			cancel()
+			require.NoError(t, <-done)
+		}
+	})
+}
+
 func TestJsonResponse(t *testing.T) {
 	response := struct {
 		OK bool `json:"ok"`
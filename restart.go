@@ -0,0 +1,189 @@
+package rest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	envInheritFDs = "REST_INHERIT_FDS" // names of listeners passed to the new process, separated by colon
+	envReadyFD    = "REST_READY_FD"    // file descriptor used by the new process to report readiness
+)
+
+// restartCommand - returns binary and arguments of the new process started on restart
+var restartCommand = func() (string, []string, error) {
+	exe, err := os.Executable()
+	return exe, os.Args[1:], err
+}
+
+// handoffListeners - returns listeners passed by the parent process on graceful restart
+func (s *Server) handoffListeners() ([]net.Listener, []string, error) {
+	value := os.Getenv(envInheritFDs)
+	readyFD := os.Getenv(envReadyFD)
+	_ = os.Unsetenv(envInheritFDs)
+	_ = os.Unsetenv(envReadyFD)
+
+	if fd, err := strconv.Atoi(readyFD); err == nil {
+		s.readyPipe = os.NewFile(uintptr(fd), "ready")
+	}
+	if value == "" {
+		return nil, nil, nil
+	}
+
+	names := strings.Split(value, ":")
+	listeners, err := fileListeners(3, len(names))
+	if err != nil {
+		return nil, nil, fmt.Errorf("inherit socket from parent process, %w", err)
+	}
+	return listeners, names, nil
+}
+
+// notifyParent - reports readiness to the parent process which started this one on restart
+func (s *Server) notifyParent() error {
+	if s.readyPipe == nil {
+		return nil
+	}
+	defer func() {
+		_ = s.readyPipe.Close()
+		s.readyPipe = nil
+	}()
+
+	if _, err := s.readyPipe.WriteString("READY=1\n"); err != nil {
+		return fmt.Errorf("notify parent process, %w", err)
+	}
+	return nil
+}
+
+// restart - starts a new process of the same binary with the current listeners and waits until it is ready.
+// The current process keeps serving if the new one fails to start.
+func (s *Server) restart() error {
+	s.mu.Lock()
+	if s.httpListener == nil {
+		s.mu.Unlock()
+		return errors.New("server is not running")
+	}
+	listeners := map[string]net.Listener{"http": s.httpListener}
+	if s.httpsListener != nil {
+		listeners["https"] = s.httpsListener
+	}
+	s.mu.Unlock()
+
+	var names []string
+	var files []*os.File
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+	for _, name := range []string{"http", "https"} {
+		ln, ok := listeners[name]
+		if !ok {
+			continue
+		}
+		filer, ok := ln.(interface{ File() (*os.File, error) })
+		if !ok {
+			return fmt.Errorf("%s listener %T can't be passed to the new process", name, ln)
+		}
+		f, err := filer.File()
+		if err != nil {
+			return fmt.Errorf("get %s listener file, %w", name, err)
+		}
+		names = append(names, name)
+		files = append(files, f)
+	}
+
+	readyReader, readyWriter, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("create readiness pipe, %w", err)
+	}
+	defer func() { _ = readyReader.Close() }()
+
+	exe, args, err := restartCommand()
+	if err != nil {
+		_ = readyWriter.Close()
+		return fmt.Errorf("get executable, %w", err)
+	}
+
+	cmd := exec.Command(exe, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = append(files, readyWriter)
+	cmd.Env = append(restartEnv(),
+		envInheritFDs+"="+strings.Join(names, ":"),
+		envReadyFD+"="+strconv.Itoa(3+len(files)),
+	)
+
+	err = cmd.Start()
+	_ = readyWriter.Close()
+	for name, ln := range listeners {
+		if e := setNonblock(ln); e != nil {
+			log.Printf("[WARN] restore non-blocking mode of %s listener, %s", name, e)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("start new process, %w", err)
+	}
+	pid := cmd.Process.Pid
+	log.Printf("[INFO] new process %d started, waiting for readiness", pid)
+
+	if err = waitReady(readyReader, s.RestartTimeout); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return fmt.Errorf("new process %d is not ready, %w", pid, err)
+	}
+	_ = cmd.Process.Release()
+
+	if err = sdNotify(fmt.Sprintf("MAINPID=%d", pid)); err != nil {
+		log.Printf("[WARN] %s", err)
+	}
+
+	// the socket file belongs to the new process now
+	for _, ln := range listeners {
+		if unixLn, ok := ln.(*net.UnixListener); ok {
+			unixLn.SetUnlinkOnClose(false)
+		}
+	}
+
+	log.Printf("[INFO] new process %d is ready", pid)
+	return nil
+}
+
+// restartEnv - environment of the current process without socket activation variables.
+// WATCHDOG_PID is dropped as well, the new process becomes the main one and pings the watchdog itself.
+func restartEnv() []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		switch strings.SplitN(kv, "=", 2)[0] {
+		case "LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES", "WATCHDOG_PID", envInheritFDs, envReadyFD:
+			continue
+		}
+		env = append(env, kv)
+	}
+	return env
+}
+
+// waitReady - waits for readiness message from the new process
+func waitReady(r *os.File, timeout time.Duration) error {
+	if err := r.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil {
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return fmt.Errorf("timeout %s exceeded", timeout)
+		}
+		return fmt.Errorf("process exited, %w", err)
+	}
+	if strings.TrimSpace(line) != "READY=1" {
+		return fmt.Errorf("unexpected message %q", line)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package rest
+
+import (
+	"net"
+	"os"
+)
+
+// restartSignals - graceful restart is not supported on this platform
+var restartSignals []os.Signal
+
+func setNonblock(_ net.Listener) error {
+	return nil
+}
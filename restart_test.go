@@ -0,0 +1,177 @@
+//go:build unix
+
+package rest
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestRestartHelperProcess - not a real test, runs the new process started by graceful restart
+func TestRestartHelperProcess(t *testing.T) {
+	if os.Getenv("REST_TEST_RESTART_HELPER") != "1" {
+		t.Skip("helper process")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	srv := &Server{}
+	err := srv.RunContext(ctx, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/exit" {
+			cancel()
+		}
+		_, _ = w.Write([]byte("child"))
+	}))
+	require.NoError(t, err)
+}
+
+func TestServer_restart(t *testing.T) {
+	t.Run("listeners passed to the new process", func(t *testing.T) {
+		setRestartCommand(t, "1")
+
+		srv := &Server{
+			Port:            freePort(t),
+			GracefulRestart: true,
+		}
+
+		done := make(chan error)
+		go func() {
+			done <- srv.RunContext(context.Background(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("parent"))
+			}))
+		}()
+		url := fmt.Sprintf("http://127.0.0.1:%d", srv.Port)
+		waitForServer(t, fmt.Sprintf("127.0.0.1:%d", srv.Port))
+		require.Equal(t, "parent", getBody(t, url))
+
+		require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR2))
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("parent is not stopped after restart")
+		}
+
+		require.Equal(t, "child", getBody(t, url))
+		require.Equal(t, "child", getBody(t, url+"/exit"))
+	})
+
+	t.Run("main pid passed to systemd", func(t *testing.T) {
+		setRestartCommand(t, "1")
+		notify := fakeNotifySocket(t)
+		t.Setenv("WATCHDOG_USEC", "20000")
+		t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+
+		srv := &Server{
+			Port:            freePort(t),
+			GracefulRestart: true,
+		}
+
+		done := make(chan error)
+		go func() {
+			done <- srv.RunContext(context.Background(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("parent"))
+			}))
+		}()
+		url := fmt.Sprintf("http://127.0.0.1:%d", srv.Port)
+		waitForServer(t, fmt.Sprintf("127.0.0.1:%d", srv.Port))
+		waitNotification(t, notify, "READY=1")
+
+		require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR2))
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("parent is not stopped after restart")
+		}
+
+		var states []string
+		buf := make([]byte, 1024)
+		require.NoError(t, notify.SetReadDeadline(time.Now().Add(200*time.Millisecond)))
+		for {
+			n, err := notify.Read(buf)
+			if err != nil {
+				break
+			}
+			states = append(states, string(buf[:n]))
+		}
+		require.NotContains(t, states, "STOPPING=1")
+
+		var mainPID string
+		for _, state := range states {
+			if pid, ok := strings.CutPrefix(state, "MAINPID="); ok {
+				mainPID = pid
+			}
+		}
+		require.NotEmpty(t, mainPID, "new main pid is not sent, %v", states)
+		require.NotEqual(t, strconv.Itoa(os.Getpid()), mainPID)
+
+		// the parent is stopped and drained, so the watchdog is pinged by the new main process
+		waitNotification(t, notify, "WATCHDOG=1")
+
+		require.Equal(t, "child", getBody(t, url+"/exit"))
+	})
+
+	t.Run("new process failed", func(t *testing.T) {
+		setRestartCommand(t, "0")
+
+		srv := &Server{
+			Port:           freePort(t),
+			RestartTimeout: time.Second,
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error)
+		go func() {
+			done <- srv.RunContext(ctx, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("parent"))
+			}))
+		}()
+		url := fmt.Sprintf("http://127.0.0.1:%d", srv.Port)
+		waitForServer(t, fmt.Sprintf("127.0.0.1:%d", srv.Port))
+
+		require.Error(t, srv.restart())
+		require.Equal(t, "parent", getBody(t, url))
+
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	t.Run("not running", func(t *testing.T) {
+		require.Error(t, (&Server{}).restart())
+	})
+}
+
+// setRestartCommand - makes restart run the helper test process, it serves requests only if helper is "1"
+func setRestartCommand(t *testing.T, helper string) {
+	t.Helper()
+
+	cmd := restartCommand
+	restartCommand = func() (string, []string, error) {
+		return os.Args[0], []string{"-test.run=^TestRestartHelperProcess$"}, nil
+	}
+	t.Setenv("REST_TEST_RESTART_HELPER", helper)
+	t.Cleanup(func() { restartCommand = cmd })
+}
+
+func getBody(t *testing.T, url string) string {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
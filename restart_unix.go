@@ -0,0 +1,33 @@
+//go:build unix
+
+package rest
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// restartSignals - signals which trigger graceful restart
+var restartSignals = []os.Signal{syscall.SIGHUP, syscall.SIGUSR2}
+
+// setNonblock - restores non-blocking mode of the listener socket.
+// Passing the socket file to the new process switches the shared descriptor to blocking mode.
+func setNonblock(ln net.Listener) error {
+	sc, ok := ln.(syscall.Conn)
+	if !ok {
+		return nil
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var nbErr error
+	if err = rc.Control(func(fd uintptr) {
+		nbErr = syscall.SetNonblock(int(fd), true)
+	}); err != nil {
+		return err
+	}
+	return nbErr
+}
@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"github.com/go-chi/chi/v5"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -18,23 +21,53 @@ type SSLConfig struct {
 	Redirect bool   // defines if http requests will be redirected to the https
 	URL      string // url where http requests will be redirected
 
+	Address  string       // https server address or unix socket as unix:/path/to.sock, Server.Address by default
+	Listener net.Listener // pre-opened https listener, Address and Port are ignored if set
+
 	CertPath string // path to the ssl certificate
 	KeyPath  string // path to the ssl key
+
+	ReloadInterval time.Duration // how often certificate files are checked for changes, 1 minute by default, negative disables reload
+
+	ClientCAPath string     // path to the CA bundle used to verify client certificates
+	ClientAuth   ClientAuth // client certificate policy, client certificates are not requested by default
+
+	Policy    TLSPolicy   // tls versions and cipher suites preset, TLSPolicyIntermediate by default, TLSConfig must not allow weaker versions or suites
+	TLSConfig *tls.Config // custom tls config used instead of the Policy preset, unset MinVersion and CipherSuites are taken from the preset, certificates and client auth from SSLConfig
 }
 
 // Server - rest server struct
 type Server struct {
-	Address string
+	Address string // listen address or unix socket as unix:/path/to.sock
 	Port    int
 	IsReady *atomic.Value
 	SSL     *SSLConfig
 
+	Listener   net.Listener // pre-opened http listener, Address and Port are ignored if set
+	SocketMode os.FileMode  // permissions of the unix socket files, 0660 by default
+
 	ReadHeaderTimeout time.Duration
 	WriteTimeout      time.Duration
 	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration // grace period for in-flight requests on shutdown
+
+	H2C   bool              // accept HTTP/2 with prior knowledge (h2c) on the http port
+	HTTP2 *http.HTTP2Config // HTTP/2 settings of the https server and h2c, like max concurrent streams and max frame size
+
+	HandleSignals bool          // stop the server gracefully on SIGINT or SIGTERM
+	PreStopDelay  time.Duration // delay between failing the readiness probe and shutdown on signal, lets load balancers deregister the server
+
+	GracefulRestart bool          // on SIGHUP or SIGUSR2 start a new process of the binary with the current listeners and stop after it is ready
+	RestartTimeout  time.Duration // how long to wait for the new process readiness, 30 seconds by default
 
-	httpServer  *http.Server
-	httpsServer *http.Server
+	Metrics *Metrics // request metrics exposed on /metrics of the default router
+	Debug   bool     // expose the text of internal errors in error responses, use it only in development
+
+	httpServer    *http.Server
+	httpsServer   *http.Server
+	httpListener  net.Listener
+	httpsListener net.Listener
+	readyPipe     *os.File
 
 	mu sync.Mutex
 }
@@ -48,6 +81,13 @@ func NewServer(port int) *Server {
 
 // Run - will initialize server and run it on provided port
 func (s *Server) Run(router http.Handler) error {
+	return s.RunContext(context.Background(), router)
+}
+
+// RunContext - will initialize server and run it until ctx is cancelled.
+// On cancellation in-flight requests are drained within ShutdownTimeout.
+// An error from any of the http/https listeners stops the server and is returned.
+func (s *Server) RunContext(ctx context.Context, router http.Handler) error {
 	if s.Address == "*" {
 		s.Address = ""
 	}
@@ -69,19 +109,40 @@ func (s *Server) Run(router http.Handler) error {
 	if s.IdleTimeout == 0 {
 		s.IdleTimeout = 60 * time.Second
 	}
+	if s.ShutdownTimeout == 0 {
+		s.ShutdownTimeout = 10 * time.Second
+	}
+	if s.SocketMode == 0 {
+		s.SocketMode = 0o660
+	}
+	if s.RestartTimeout == 0 {
+		s.RestartTimeout = 30 * time.Second
+	}
 
 	if router == nil {
 		mux := chi.NewRouter()
+		if s.Metrics != nil {
+			mux.Use(s.Metrics.Middleware)
+		}
 		mux.Use(Readiness("/readiness", s.IsReady))
 		mux.HandleFunc("/ping", okHandler)
 		mux.HandleFunc("/liveness", okHandler)
+		if s.Metrics != nil {
+			mux.Method(http.MethodGet, "/metrics", s.Metrics)
+		}
 		router = mux
 	}
 
-	log.Printf("[INFO] http rest server on %s:%d", s.Address, s.Port)
+	if err := s.inheritListeners(); err != nil {
+		return err
+	}
 
 	httpRouter := router
 
+	// all listeners are bound and the keypair is loaded before serving,
+	// so misconfiguration fails the Run instead of leaving half of the service running
+	var httpsServer *http.Server
+	var httpsListener net.Listener
 	if s.SSL != nil {
 		if s.SSL.Port == 0 {
 			s.SSL.Port = s.Port + 1
@@ -108,83 +169,228 @@ func (s *Server) Run(router http.Handler) error {
 			return fmt.Errorf("ssl key not found: %s", s.SSL.KeyPath)
 		}
 
-		log.Printf("[INFO] https rest server on %s:%d", s.Address, s.SSL.Port)
+		certs, err := newCertReloader(s.SSL.CertPath, s.SSL.KeyPath)
+		if err != nil {
+			return err
+		}
+		tlsConfig, err := s.SSL.tlsConfig()
+		if err != nil {
+			return err
+		}
+		tlsConfig.Certificates = nil
+		tlsConfig.GetCertificate = certs.GetCertificate
+		if s.SSL.ReloadInterval == 0 {
+			s.SSL.ReloadInterval = time.Minute
+		}
+		if s.SSL.ReloadInterval > 0 {
+			watchCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			go certs.watch(watchCtx, s.SSL.ReloadInterval)
+		}
+
+		httpsAddress := s.Address
+		if s.SSL.Address != "" {
+			httpsAddress = s.SSL.Address
+		}
+		if s.SSL.Listener == nil && isUnixAddress(httpsAddress) && httpsAddress == s.Address && s.Listener == nil {
+			return fmt.Errorf("https server requires its own unix socket: %s", httpsAddress)
+		}
 
-		s.mu.Lock()
-		s.httpsServer = s.https(s.Address, s.SSL.Port, router)
-		s.mu.Unlock()
+		httpsListener, err = s.listen(s.SSL.Listener, httpsAddress, s.SSL.Port)
+		if err != nil {
+			return fmt.Errorf("start https server, %w", err)
+		}
 
-		go func() {
-			log.Printf("[WARN] https server terminated, %s", s.httpsServer.ListenAndServeTLS(s.SSL.CertPath, s.SSL.KeyPath))
-		}()
+		log.Printf("[INFO] https rest server on %s", httpsListener.Addr())
+
+		httpsServer = s.https(s.Address, s.SSL.Port, router, tlsConfig)
+	}
+
+	httpListener, err := s.listen(s.Listener, s.Address, s.Port)
+	if err != nil {
+		if httpsListener != nil {
+			_ = httpsListener.Close()
+		}
+		return fmt.Errorf("start http server, %w", err)
 	}
 
+	log.Printf("[INFO] http rest server on %s", httpListener.Addr())
+
+	httpServer := s.http(s.Address, s.Port, httpRouter)
+
 	s.mu.Lock()
-	s.httpServer = s.http(s.Address, s.Port, httpRouter)
+	s.httpServer = httpServer
+	s.httpsServer = httpsServer
+	s.httpListener = httpListener
+	s.httpsListener = httpsListener
 	s.mu.Unlock()
 
-	if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		return fmt.Errorf("start http server, %s", err)
+	var stop chan os.Signal
+	if s.HandleSignals {
+		stop = make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(stop)
+	}
+	var restart chan os.Signal
+	if s.GracefulRestart && len(restartSignals) > 0 {
+		restart = make(chan os.Signal, 1)
+		signal.Notify(restart, restartSignals...)
+		defer signal.Stop(restart)
 	}
 
-	return nil
+	errCh := make(chan error, 2)
+	if httpsServer != nil {
+		go func() {
+			if err := httpsServer.ServeTLS(httpsListener, "", ""); err != nil && err != http.ErrServerClosed {
+				errCh <- fmt.Errorf("https server terminated, %w", err)
+				return
+			}
+			errCh <- nil
+		}()
+	}
+	go func() {
+		if err := httpServer.Serve(httpListener); err != nil && err != http.ErrServerClosed {
+			errCh <- fmt.Errorf("http server terminated, %w", err)
+			return
+		}
+		errCh <- nil
+	}()
+
+	notifyCtx, cancelNotify := context.WithCancel(ctx)
+	defer cancelNotify()
+	go s.notifyState(notifyCtx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), s.ShutdownTimeout)
+			defer cancel()
+
+			log.Print("[INFO] shutdown rest server, context cancelled")
+			return s.shutdown(shutdownCtx)
+		case sig := <-stop:
+			log.Printf("[INFO] %s received, stopping rest server", sig)
+			s.IsReady.Store(false)
+			if s.PreStopDelay > 0 {
+				log.Printf("[INFO] waiting %s before shutdown", s.PreStopDelay)
+				select {
+				case <-time.After(s.PreStopDelay):
+				case <-stop: // repeated signal skips the delay
+				case <-ctx.Done():
+				}
+			}
+
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), s.ShutdownTimeout)
+			defer cancel()
+
+			return s.shutdown(shutdownCtx)
+		case sig := <-restart:
+			log.Printf("[INFO] restart rest server, %s received", sig)
+			if err := s.restart(); err != nil {
+				log.Printf("[ERROR] restart rest server, %s", err)
+				continue
+			}
+
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), s.ShutdownTimeout)
+			defer cancel()
+
+			// the service is not stopping, systemd already tracks the new process as the main one
+			log.Print("[INFO] shutdown rest server, listeners passed to the new process")
+			return s.stopServers(shutdownCtx)
+		case err := <-errCh:
+			if err == nil {
+				// closed by Shutdown
+				return nil
+			}
+
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), s.ShutdownTimeout)
+			defer cancel()
+
+			log.Printf("[ERROR] %s", err)
+			if e := s.shutdown(shutdownCtx); e != nil {
+				log.Printf("[WARN] shutdown rest server, %s", e)
+			}
+			return err
+		}
+	}
 }
 
 // Shutdown - shutdown rest server
 func (s *Server) Shutdown() error {
 	log.Print("[INFO] shutdown rest server")
 
+	timeout := s.ShutdownTimeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return s.shutdown(ctx)
+}
+
+// shutdown - notifies systemd about stopping and stops the servers
+func (s *Server) shutdown(ctx context.Context) error {
+	if err := sdNotify("STOPPING=1"); err != nil {
+		log.Printf("[WARN] %s", err)
+	}
+	return s.stopServers(ctx)
+}
+
+// stopServers - gracefully stops http and https servers, both are stopped even if one of them fails
+func (s *Server) stopServers(ctx context.Context) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	var err error
 	if s.httpServer != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
-		defer cancel()
-
-		if err := s.httpServer.Shutdown(ctx); err != nil {
-			return err
+		if e := s.httpServer.Shutdown(ctx); e != nil {
+			err = fmt.Errorf("shutdown http server, %w", e)
 		}
 	}
 	if s.httpsServer != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
-		defer cancel()
-
-		if err := s.httpsServer.Shutdown(ctx); err != nil {
-			return err
+		if e := s.httpsServer.Shutdown(ctx); e != nil && err == nil {
+			err = fmt.Errorf("shutdown https server, %w", e)
 		}
 	}
 
-	return nil
+	return err
+}
+
+// isReady - returns current value of the readiness flag
+func (s *Server) isReady() bool {
+	if s.IsReady == nil {
+		return false
+	}
+	ready, _ := s.IsReady.Load().(bool)
+	return ready
 }
 
 func (s *Server) http(address string, port int, router http.Handler) *http.Server {
-	return &http.Server{
+	server := &http.Server{
 		Addr:              fmt.Sprintf("%s:%d", address, port),
 		Handler:           router,
 		ReadHeaderTimeout: s.ReadHeaderTimeout,
 		WriteTimeout:      s.WriteTimeout,
 		IdleTimeout:       s.IdleTimeout,
+		HTTP2:             s.HTTP2,
+	}
+	if s.Debug {
+		server.BaseContext = func(net.Listener) context.Context {
+			return ContextWithDebug(context.Background())
+		}
+	}
+	if s.H2C {
+		server.Protocols = new(http.Protocols)
+		server.Protocols.SetHTTP1(true)
+		server.Protocols.SetUnencryptedHTTP2(true)
 	}
+	return server
 }
-func (s *Server) https(address string, port int, router http.Handler) *http.Server {
+func (s *Server) https(address string, port int, router http.Handler, tlsConfig *tls.Config) *http.Server {
 	server := s.http(address, port, router)
-	server.TLSConfig = &tls.Config{
-		CipherSuites: []uint16{
-			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
-			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
-			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
-			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
-			tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
-		},
-		MinVersion: tls.VersionTLS12,
-		CurvePreferences: []tls.CurveID{
-			tls.CurveP256,
-			tls.X25519,
-			tls.CurveP384,
-		},
-	}
+	server.TLSConfig = tlsConfig
+	server.Protocols = nil
 	return server
 }
 
@@ -1,9 +1,22 @@
 package rest
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"github.com/stretchr/testify/require"
+	"math/big"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -23,11 +36,15 @@ func TestServer_Run(t *testing.T) {
 				Address: "*",
 				Port:    8123,
 			}
+			defer func() {
+				require.NoError(t, srv.Shutdown())
+			}()
 
 			handler := new(handlerFunc)
 			go func() {
 				require.NoError(t, srv.Run(handler))
 			}()
+			waitForServer(t, "localhost:8123")
 
 			req, err := http.Get(fmt.Sprintf("http://localhost:%d", srv.Port))
 			require.NoError(t, err)
@@ -38,10 +55,15 @@ func TestServer_Run(t *testing.T) {
 		t.Run("empty port", func(t *testing.T) {
 			srv := &Server{}
 
+			defer func() {
+				require.NoError(t, srv.Shutdown())
+			}()
+
 			handler := new(handlerFunc)
 			go func() {
 				require.NoError(t, srv.Run(handler))
 			}()
+			waitForServer(t, "localhost:8080")
 
 			req, err := http.Get(fmt.Sprintf("http://localhost:8080"))
 			require.NoError(t, err)
@@ -60,6 +82,7 @@ func TestServer_Run(t *testing.T) {
 			go func() {
 				require.NoError(t, srv.Run(nil))
 			}()
+			waitForServer(t, "localhost:1234")
 
 			host := fmt.Sprintf("http://localhost:%d", srv.Port)
 			resp, err := http.Get(host + "/ping")
@@ -177,3 +200,386 @@ func TestServer_Shutdown(t *testing.T) {
 
 	require.NoError(t, srv.Run(handler))
 }
+
+func TestServer_RunContext(t *testing.T) {
+	t.Run("stop on context cancel", func(t *testing.T) {
+		srv := &Server{
+			Port: freePort(t),
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error)
+		go func() {
+			done <- srv.RunContext(ctx, new(handlerFunc))
+		}()
+		addr := fmt.Sprintf("localhost:%d", srv.Port)
+		waitForServer(t, addr)
+
+		cancel()
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("server not stopped after context cancel")
+		}
+
+		_, err := net.Dial("tcp", addr)
+		require.Error(t, err)
+	})
+
+	t.Run("drain in-flight requests", func(t *testing.T) {
+		srv := &Server{
+			Port:            freePort(t),
+			ShutdownTimeout: time.Second,
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		started := make(chan struct{})
+
+		done := make(chan error)
+		go func() {
+			done <- srv.RunContext(ctx, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				time.Sleep(100 * time.Millisecond)
+				w.WriteHeader(http.StatusAccepted)
+			}))
+		}()
+		waitForServer(t, fmt.Sprintf("localhost:%d", srv.Port))
+
+		go func() {
+			<-started
+			cancel()
+		}()
+
+		resp, err := http.Get(fmt.Sprintf("http://localhost:%d", srv.Port))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+		require.NoError(t, <-done)
+	})
+
+	t.Run("grace period exceeded", func(t *testing.T) {
+		srv := &Server{
+			Port:            freePort(t),
+			ShutdownTimeout: 10 * time.Millisecond,
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		started := make(chan struct{})
+		release := make(chan struct{})
+		defer close(release)
+
+		done := make(chan error)
+		go func() {
+			done <- srv.RunContext(ctx, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				<-release
+			}))
+		}()
+		waitForServer(t, fmt.Sprintf("localhost:%d", srv.Port))
+
+		go func() {
+			_, _ = http.Get(fmt.Sprintf("http://localhost:%d", srv.Port))
+		}()
+		<-started
+		cancel()
+
+		require.True(t, errors.Is(<-done, context.DeadlineExceeded))
+	})
+
+	t.Run("https keypair error", func(t *testing.T) {
+		dir := t.TempDir()
+		certPath, keyPath := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+		require.NoError(t, os.WriteFile(certPath, []byte("not a cert"), 0o600))
+		require.NoError(t, os.WriteFile(keyPath, []byte("not a key"), 0o600))
+
+		srv := &Server{
+			Port: freePort(t),
+			SSL: &SSLConfig{
+				Port:     freePort(t),
+				CertPath: certPath,
+				KeyPath:  keyPath,
+			},
+		}
+
+		err := srv.RunContext(context.Background(), new(handlerFunc))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "keypair")
+
+		_, err = net.Dial("tcp", fmt.Sprintf("localhost:%d", srv.Port))
+		require.Error(t, err)
+	})
+
+	t.Run("https port in use", func(t *testing.T) {
+		certPath, keyPath := generateCert(t, t.TempDir(), "server")
+
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer func() { _ = ln.Close() }()
+
+		srv := &Server{
+			Address: "127.0.0.1",
+			Port:    freePort(t),
+			SSL: &SSLConfig{
+				Port:     ln.Addr().(*net.TCPAddr).Port,
+				CertPath: certPath,
+				KeyPath:  keyPath,
+			},
+		}
+
+		err = srv.RunContext(context.Background(), new(handlerFunc))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "start https server")
+
+		_, err = net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", srv.Port))
+		require.Error(t, err)
+	})
+
+	t.Run("http port in use", func(t *testing.T) {
+		certPath, keyPath := generateCert(t, t.TempDir(), "server")
+
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer func() { _ = ln.Close() }()
+
+		srv := &Server{
+			Address: "127.0.0.1",
+			Port:    ln.Addr().(*net.TCPAddr).Port,
+			SSL: &SSLConfig{
+				Port:     freePort(t),
+				CertPath: certPath,
+				KeyPath:  keyPath,
+			},
+		}
+
+		err = srv.RunContext(context.Background(), new(handlerFunc))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "start http server")
+
+		_, err = net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", srv.SSL.Port))
+		require.Error(t, err)
+	})
+
+	t.Run("http and https", func(t *testing.T) {
+		certPath, keyPath := generateCert(t, t.TempDir(), "server")
+
+		srv := &Server{
+			Port: freePort(t),
+			SSL: &SSLConfig{
+				Port:     freePort(t),
+				CertPath: certPath,
+				KeyPath:  keyPath,
+			},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error)
+		go func() {
+			done <- srv.RunContext(ctx, nil)
+		}()
+		waitForServer(t, fmt.Sprintf("localhost:%d", srv.SSL.Port))
+
+		resp, err := http.Get(fmt.Sprintf("http://localhost:%d/ping", srv.Port))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp, err = insecureClient().Get(fmt.Sprintf("https://localhost:%d/ping", srv.SSL.Port))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		cancel()
+		require.NoError(t, <-done)
+	})
+}
+
+// waitForServer - waits until server accepts connections on addr
+func waitForServer(t *testing.T, addr string) {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			_ = conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("server on %s is not started", addr)
+}
+
+// freePort - returns a random free tcp port
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// insecureClient - http client which accepts self-signed certificates
+func insecureClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+}
+
+// generateCert - writes self-signed certificate and key for localhost into dir
+func generateCert(t *testing.T, dir string, name string) (certPath, keyPath string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPath, keyPath = filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600))
+
+	return certPath, keyPath
+}
+
+func TestServer_RunContext_signals(t *testing.T) {
+	srv := &Server{
+		Port:          freePort(t),
+		HandleSignals: true,
+		PreStopDelay:  200 * time.Millisecond,
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- srv.RunContext(context.Background(), nil)
+	}()
+	host := fmt.Sprintf("http://localhost:%d", srv.Port)
+	waitForServer(t, fmt.Sprintf("localhost:%d", srv.Port))
+
+	resp, err := http.Get(host + "/readiness")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	p, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+	require.NoError(t, p.Signal(os.Interrupt))
+
+	for i := 0; i < 100 && srv.isReady(); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	resp, err = http.Get(host + "/readiness")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("server is not stopped after signal")
+	}
+}
+
+func TestServer_RunContext_HTTP2(t *testing.T) {
+	h2cClient := func() *http.Client {
+		protocols := new(http.Protocols)
+		protocols.SetUnencryptedHTTP2(true)
+		return &http.Client{Transport: &http.Transport{Protocols: protocols}}
+	}
+
+	t.Run("h2c", func(t *testing.T) {
+		srv := &Server{
+			Port: freePort(t),
+			H2C:  true,
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error)
+		go func() {
+			done <- srv.RunContext(ctx, nil)
+		}()
+		waitForServer(t, fmt.Sprintf("localhost:%d", srv.Port))
+
+		resp, err := h2cClient().Get(fmt.Sprintf("http://localhost:%d/ping", srv.Port))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, 2, resp.ProtoMajor)
+
+		resp, err = http.Get(fmt.Sprintf("http://localhost:%d/ping", srv.Port))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, 1, resp.ProtoMajor)
+
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	t.Run("h2c disabled", func(t *testing.T) {
+		srv := &Server{
+			Port: freePort(t),
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error)
+		go func() {
+			done <- srv.RunContext(ctx, nil)
+		}()
+		waitForServer(t, fmt.Sprintf("localhost:%d", srv.Port))
+
+		_, err := h2cClient().Get(fmt.Sprintf("http://localhost:%d/ping", srv.Port))
+		require.Error(t, err)
+
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	t.Run("https settings", func(t *testing.T) {
+		certPath, keyPath := generateCert(t, t.TempDir(), "server")
+
+		srv := &Server{
+			Port: freePort(t),
+			SSL: &SSLConfig{
+				Port:     freePort(t),
+				CertPath: certPath,
+				KeyPath:  keyPath,
+			},
+			HTTP2: &http.HTTP2Config{
+				MaxConcurrentStreams: 10,
+				MaxReadFrameSize:     1 << 20,
+			},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error)
+		go func() {
+			done <- srv.RunContext(ctx, nil)
+		}()
+		waitForServer(t, fmt.Sprintf("localhost:%d", srv.SSL.Port))
+
+		srv.mu.Lock()
+		require.Equal(t, srv.HTTP2, srv.httpsServer.HTTP2)
+		srv.mu.Unlock()
+
+		client := insecureClient()
+		client.Transport.(*http.Transport).ForceAttemptHTTP2 = true
+		resp, err := client.Get(fmt.Sprintf("https://localhost:%d/ping", srv.SSL.Port))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, 2, resp.ProtoMajor)
+
+		cancel()
+		require.NoError(t, <-done)
+	})
+}
@@ -0,0 +1,119 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"iter"
+	"net/http"
+)
+
+// streamFlushSize - amount of encoded data buffered before it is written and flushed to the client
+const streamFlushSize = 32 << 10
+
+// StreamJSON - writes elements of seq as a JSON array without building the whole response in memory.
+// Data is flushed to the client in chunks. If encoding fails before any data is sent the response is 500,
+// later errors stop the stream and leave the array unterminated, so the client sees the invalid body.
+func StreamJSON[T any](w http.ResponseWriter, code int, seq iter.Seq[T]) error {
+	s := newJSONStream(w, code)
+	for v := range seq {
+		if err := s.add(v); err != nil {
+			return err
+		}
+	}
+	return s.close()
+}
+
+// StreamJSONChan - writes values received from ch as a JSON array until ch is closed, see StreamJSON.
+// Buffered data is also flushed whenever ch has no value ready, so slow producers don't delay the client.
+// The stream stops on errors without draining ch, so senders should also watch the request context.
+func StreamJSONChan[T any](w http.ResponseWriter, code int, ch <-chan T) error {
+	s := newJSONStream(w, code)
+	for {
+		var v T
+		var ok bool
+		select {
+		case v, ok = <-ch:
+		default:
+			if err := s.idle(); err != nil {
+				return err
+			}
+			v, ok = <-ch
+		}
+		if !ok {
+			return s.close()
+		}
+		if err := s.add(v); err != nil {
+			return err
+		}
+	}
+}
+
+// jsonStream - JSON array written to the response in chunks
+type jsonStream struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	code    int
+	buf     *bytes.Buffer
+	started bool // headers are written
+	empty   bool // no elements added yet
+}
+
+func newJSONStream(w http.ResponseWriter, code int) *jsonStream {
+	s := &jsonStream{w: w, rc: http.NewResponseController(w), code: code, buf: &bytes.Buffer{}, empty: true}
+	s.buf.WriteByte('[')
+	return s
+}
+
+// add - encodes the element, buffered data is flushed when it exceeds streamFlushSize
+func (s *jsonStream) add(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		if !s.started {
+			http.Error(s.w, err.Error(), http.StatusInternalServerError)
+		}
+		return err
+	}
+	if !s.empty {
+		s.buf.WriteByte(',')
+	}
+	s.empty = false
+	s.buf.Write(b)
+
+	if s.buf.Len() >= streamFlushSize {
+		return s.flush()
+	}
+	return nil
+}
+
+// idle - flushes buffered elements while waiting for the next one, headers are not sent before the first element
+func (s *jsonStream) idle() error {
+	if s.empty || s.buf.Len() == 0 {
+		return nil
+	}
+	return s.flush()
+}
+
+// close - terminates the array and flushes the rest of the data
+func (s *jsonStream) close() error {
+	s.buf.WriteString("]\n")
+	return s.flush()
+}
+
+func (s *jsonStream) flush() error {
+	if !s.started {
+		if s.w.Header().Get("Content-Type") == "" {
+			s.w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		}
+		s.w.WriteHeader(s.code)
+		s.started = true
+	}
+	if _, err := s.w.Write(s.buf.Bytes()); err != nil {
+		return err
+	}
+	s.buf.Reset()
+	if err := s.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
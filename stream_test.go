@@ -0,0 +1,177 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type countingWriter struct {
+	*httptest.ResponseRecorder
+	writes    int
+	failAfter int // writes after this number fail, zero disables failures
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	w.writes++
+	if w.failAfter > 0 && w.writes > w.failAfter {
+		return 0, errors.New("connection reset")
+	}
+	return w.ResponseRecorder.Write(b)
+}
+
+func TestStreamJSON(t *testing.T) {
+	t.Run("slice", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		err := StreamJSON(w, http.StatusOK, slices.Values([]renderItem{{ID: 1, Name: "<b>"}, {ID: 2, Name: "pen"}}))
+		require.NoError(t, err)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+		require.Equal(t, `[{"id":1,"name":"\u003cb\u003e"},{"id":2,"name":"pen"}]`+"\n", w.Body.String())
+		require.True(t, w.Flushed)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		require.NoError(t, StreamJSON(w, http.StatusAccepted, slices.Values([]int(nil))))
+		require.Equal(t, http.StatusAccepted, w.Code)
+		require.Equal(t, "[]\n", w.Body.String())
+	})
+
+	t.Run("content type is kept", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		require.NoError(t, StreamJSON(w, http.StatusOK, slices.Values([]int{1})))
+		require.Equal(t, "application/vnd.api+json", w.Header().Get("Content-Type"))
+	})
+
+	t.Run("large", func(t *testing.T) {
+		items := make([]renderItem, 10000)
+		for i := range items {
+			items[i] = renderItem{ID: i, Name: strings.Repeat("x", 10)}
+		}
+
+		w := &countingWriter{ResponseRecorder: httptest.NewRecorder()}
+		require.NoError(t, StreamJSON(w, http.StatusOK, slices.Values(items)))
+		require.True(t, w.writes > 1, "data is written in chunks")
+
+		var decoded []renderItem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &decoded))
+		require.Equal(t, items, decoded)
+	})
+
+	t.Run("encoding error before data is sent", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		err := StreamJSON(w, http.StatusOK, slices.Values([]*custom{{test: "test"}}))
+		require.Error(t, err)
+
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		require.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+		require.Equal(t, "json: error calling MarshalJSON for type *rest.custom: test\n", w.Body.String())
+	})
+
+	t.Run("encoding error after data is sent", func(t *testing.T) {
+		seq := func(yield func(interface{}) bool) {
+			if !yield(strings.Repeat("x", streamFlushSize)) {
+				return
+			}
+			yield(&custom{test: "test"})
+		}
+
+		w := httptest.NewRecorder()
+		err := StreamJSON(w, http.StatusOK, seq)
+		require.Error(t, err)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.True(t, strings.HasPrefix(w.Body.String(), `["xxx`))
+		require.False(t, json.Valid(w.Body.Bytes()))
+	})
+
+	t.Run("write error stops the stream", func(t *testing.T) {
+		produced := 0
+		seq := func(yield func(string) bool) {
+			for {
+				produced++
+				if !yield(strings.Repeat("x", 1024)) {
+					return
+				}
+			}
+		}
+
+		w := &countingWriter{ResponseRecorder: httptest.NewRecorder(), failAfter: 1}
+		err := StreamJSON(w, http.StatusOK, seq)
+		require.EqualError(t, err, "connection reset")
+		require.True(t, produced < 100)
+	})
+}
+
+func TestStreamJSONChan(t *testing.T) {
+	t.Run("values", func(t *testing.T) {
+		ch := make(chan int)
+		go func() {
+			defer close(ch)
+			for i := 1; i <= 3; i++ {
+				ch <- i
+			}
+		}()
+
+		w := httptest.NewRecorder()
+		require.NoError(t, StreamJSONChan(w, http.StatusOK, ch))
+		require.Equal(t, "[1,2,3]\n", w.Body.String())
+	})
+
+	t.Run("ready values are buffered", func(t *testing.T) {
+		ch := make(chan int, 1000)
+		for i := 0; i < cap(ch); i++ {
+			ch <- i
+		}
+		close(ch)
+
+		w := &countingWriter{ResponseRecorder: httptest.NewRecorder()}
+		require.NoError(t, StreamJSONChan(w, http.StatusOK, ch))
+		require.Equal(t, 1, w.writes)
+		require.True(t, strings.HasPrefix(w.Body.String(), "[0,1,2,"))
+	})
+
+	t.Run("slow producer", func(t *testing.T) {
+		release := make(chan struct{})
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ch := make(chan int)
+			go func() {
+				defer close(ch)
+				ch <- 1
+				<-release
+				ch <- 2
+			}()
+			_ = StreamJSONChan(w, http.StatusOK, ch)
+		}))
+		defer ts.Close()
+		var releaseOnce sync.Once
+		unblock := func() { releaseOnce.Do(func() { close(release) }) }
+		defer unblock()
+
+		client := &http.Client{Timeout: 5 * time.Second}
+		resp, err := client.Get(ts.URL)
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+
+		first := make([]byte, 2)
+		_, err = io.ReadFull(resp.Body, first)
+		require.NoError(t, err)
+		require.Equal(t, "[1", string(first), "first element is sent before the producer finishes")
+
+		unblock()
+		rest, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, ",2]\n", string(rest))
+	})
+}
@@ -0,0 +1,182 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listenFDsStart - first file descriptor passed by systemd socket activation
+var listenFDsStart = 3
+
+// systemdListeners - returns listeners inherited from systemd socket activation with their names from LISTEN_FDNAMES.
+// Environment variables are unset, so child processes do not inherit them.
+func systemdListeners() ([]net.Listener, []string, error) {
+	defer func() {
+		_ = os.Unsetenv("LISTEN_PID")
+		_ = os.Unsetenv("LISTEN_FDS")
+		_ = os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil, nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, nil, nil
+	}
+
+	var names []string
+	if v := os.Getenv("LISTEN_FDNAMES"); v != "" {
+		names = strings.Split(v, ":")
+	}
+
+	listeners, err := fileListeners(listenFDsStart, count)
+	if err != nil {
+		return nil, nil, fmt.Errorf("inherit systemd socket, %w", err)
+	}
+
+	for len(names) < count {
+		names = append(names, "")
+	}
+
+	return listeners, names[:count], nil
+}
+
+// fileListeners - creates listeners from count inherited file descriptors starting from start
+func fileListeners(start, count int) ([]net.Listener, error) {
+	listeners := make([]net.Listener, 0, count)
+	for i := 0; i < count; i++ {
+		f := os.NewFile(uintptr(start+i), fmt.Sprintf("LISTEN_FD_%d", start+i))
+		ln, err := net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			return nil, fmt.Errorf("file descriptor %d, %w", start+i, err)
+		}
+		listeners = append(listeners, ln)
+	}
+	return listeners, nil
+}
+
+// inheritListeners - assigns sockets passed by the parent process on restart or by systemd to the http and https servers.
+// Sockets named "http" and "https" are matched by name, unnamed ones by position.
+func (s *Server) inheritListeners() error {
+	source := "parent process"
+	listeners, names, err := s.handoffListeners()
+	if err != nil {
+		return err
+	}
+	if len(listeners) == 0 {
+		source = "systemd"
+		if listeners, names, err = systemdListeners(); err != nil {
+			return err
+		}
+	}
+
+	for i, ln := range listeners {
+		name := names[i]
+		if name != "http" && name != "https" {
+			name = "http"
+			if i == 1 {
+				name = "https"
+			}
+		}
+
+		switch {
+		case name == "http" && s.Listener == nil:
+			s.Listener = ln
+		case name == "https" && s.SSL != nil && s.SSL.Listener == nil:
+			s.SSL.Listener = ln
+		default:
+			log.Printf("[WARN] unused socket %s from %s", ln.Addr(), source)
+			_ = ln.Close()
+			continue
+		}
+		log.Printf("[INFO] %s server uses socket %s from %s", name, ln.Addr(), source)
+	}
+
+	return nil
+}
+
+// sdNotify - sends the state to the systemd notify socket, does nothing if NOTIFY_SOCKET is not set
+func sdNotify(state string) error {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return nil
+	}
+	if strings.HasPrefix(addr, "@") {
+		addr = "\x00" + addr[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("dial systemd notify socket, %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if _, err = conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("send systemd notification, %w", err)
+	}
+	return nil
+}
+
+// watchdogInterval - returns systemd watchdog timeout for this process, zero if the watchdog is disabled
+func watchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// notifyState - notifies systemd and the parent process once the server is ready and pings the watchdog until ctx is done
+func (s *Server) notifyState(ctx context.Context) {
+	if os.Getenv("NOTIFY_SOCKET") == "" && s.readyPipe == nil {
+		return
+	}
+
+	interval := time.Second
+	watchdog := watchdogInterval()
+	if watchdog > 0 && watchdog/2 < interval {
+		interval = watchdog / 2
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	ready := false
+	for {
+		if !ready && s.isReady() {
+			if err := sdNotify("READY=1"); err != nil {
+				log.Printf("[WARN] %s", err)
+			}
+			if err := s.notifyParent(); err != nil {
+				log.Printf("[WARN] %s", err)
+			}
+			ready = true
+		}
+		if watchdog > 0 {
+			if err := sdNotify("WATCHDOG=1"); err != nil {
+				log.Printf("[WARN] %s", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSystemdListeners(t *testing.T) {
+	t.Run("not activated", func(t *testing.T) {
+		t.Setenv("LISTEN_PID", "")
+		t.Setenv("LISTEN_FDS", "")
+
+		listeners, names, err := systemdListeners()
+		require.NoError(t, err)
+		require.Empty(t, listeners)
+		require.Empty(t, names)
+	})
+
+	t.Run("other process", func(t *testing.T) {
+		t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+		t.Setenv("LISTEN_FDS", "1")
+
+		listeners, _, err := systemdListeners()
+		require.NoError(t, err)
+		require.Empty(t, listeners)
+		require.Empty(t, os.Getenv("LISTEN_FDS"))
+	})
+
+	t.Run("inherited socket", func(t *testing.T) {
+		ln := inheritListener(t, "https")
+
+		listeners, names, err := systemdListeners()
+		require.NoError(t, err)
+		require.Len(t, listeners, 1)
+		require.Equal(t, []string{"https"}, names)
+		require.Equal(t, ln.Addr().String(), listeners[0].Addr().String())
+		require.NoError(t, listeners[0].Close())
+
+		require.Empty(t, os.Getenv("LISTEN_PID"))
+		require.Empty(t, os.Getenv("LISTEN_FDS"))
+		require.Empty(t, os.Getenv("LISTEN_FDNAMES"))
+	})
+
+	t.Run("not a socket", func(t *testing.T) {
+		f, err := os.CreateTemp(t.TempDir(), "fd")
+		require.NoError(t, err)
+		defer func() { _ = f.Close() }()
+
+		setListenFDsStart(t, int(f.Fd()))
+		t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+		t.Setenv("LISTEN_FDS", "1")
+
+		_, _, err = systemdListeners()
+		require.Error(t, err)
+	})
+}
+
+func TestServer_RunContext_systemd(t *testing.T) {
+	t.Run("socket activation", func(t *testing.T) {
+		ln := inheritListener(t, "")
+
+		srv := &Server{}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error)
+		go func() {
+			done <- srv.RunContext(ctx, nil)
+		}()
+		waitForServer(t, ln.Addr().String())
+
+		resp, err := http.Get(fmt.Sprintf("http://%s/ping", ln.Addr()))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	t.Run("notify", func(t *testing.T) {
+		notify := fakeNotifySocket(t)
+		t.Setenv("WATCHDOG_USEC", "20000")
+		t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+
+		srv := &Server{
+			Port:    freePort(t),
+			IsReady: &atomic.Value{},
+		}
+		srv.IsReady.Store(false)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error)
+		go func() {
+			done <- srv.RunContext(ctx, nil)
+		}()
+
+		require.Equal(t, "WATCHDOG=1", readNotification(t, notify))
+		srv.IsReady.Store(true)
+		waitNotification(t, notify, "READY=1")
+
+		cancel()
+		require.NoError(t, <-done)
+		waitNotification(t, notify, "STOPPING=1")
+	})
+
+	t.Run("watchdog of other process", func(t *testing.T) {
+		t.Setenv("WATCHDOG_USEC", "20000")
+		t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()+1))
+		require.Zero(t, watchdogInterval())
+	})
+}
+
+// inheritListener - emulates systemd socket activation with a single tcp socket
+func inheritListener(t *testing.T, name string) net.Listener {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	f, err := ln.(*net.TCPListener).File()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = f.Close() })
+
+	setListenFDsStart(t, int(f.Fd()))
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDS", "1")
+	t.Setenv("LISTEN_FDNAMES", name)
+
+	return ln
+}
+
+func setListenFDsStart(t *testing.T, fd int) {
+	t.Helper()
+
+	start := listenFDsStart
+	listenFDsStart = fd
+	t.Cleanup(func() { listenFDsStart = start })
+}
+
+// fakeNotifySocket - creates unixgram socket and points NOTIFY_SOCKET to it
+func fakeNotifySocket(t *testing.T) *net.UnixConn {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	t.Setenv("NOTIFY_SOCKET", path)
+	return conn
+}
+
+func readNotification(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	buf := make([]byte, 1024)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+
+	return string(buf[:n])
+}
+
+// waitNotification - reads notifications until the state is received
+func waitNotification(t *testing.T, conn *net.UnixConn, state string) {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		if readNotification(t, conn) == state {
+			return
+		}
+	}
+	t.Fatalf("%s is not received", state)
+}
@@ -0,0 +1,144 @@
+package rest
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+)
+
+// TLSPolicy - named preset of tls versions, cipher suites and curves
+type TLSPolicy string
+
+const (
+	TLSPolicyModern       TLSPolicy = "modern"       // TLS 1.3 only
+	TLSPolicyIntermediate TLSPolicy = "intermediate" // TLS 1.2 and newer with AEAD cipher suites
+	TLSPolicyLegacy       TLSPolicy = "legacy"       // TLS 1.0 and newer with CBC cipher suites for old clients
+)
+
+var intermediateCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+}
+
+var legacyCipherSuites = append(append([]uint16{}, intermediateCipherSuites...),
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+)
+
+var defaultCurves = []tls.CurveID{
+	tls.X25519,
+	tls.CurveP256,
+	tls.CurveP384,
+}
+
+// config - returns new tls config for the policy
+func (p TLSPolicy) config() (*tls.Config, error) {
+	switch p {
+	case TLSPolicyModern:
+		return &tls.Config{
+			MinVersion:       tls.VersionTLS13,
+			CurvePreferences: append([]tls.CurveID{}, defaultCurves...),
+		}, nil
+	case TLSPolicyIntermediate, "":
+		return &tls.Config{
+			MinVersion:       tls.VersionTLS12,
+			CipherSuites:     append([]uint16{}, intermediateCipherSuites...),
+			CurvePreferences: append([]tls.CurveID{}, defaultCurves...),
+		}, nil
+	case TLSPolicyLegacy:
+		return &tls.Config{
+			MinVersion:       tls.VersionTLS10,
+			CipherSuites:     append([]uint16{}, legacyCipherSuites...),
+			CurvePreferences: append([]tls.CurveID{}, defaultCurves...),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown tls policy: %s", p)
+}
+
+// tlsConfig - builds tls config for the https server from the custom config or the policy preset
+func (c *SSLConfig) tlsConfig() (*tls.Config, error) {
+	cfg, err := c.Policy.config()
+	if err != nil {
+		return nil, err
+	}
+	if c.TLSConfig != nil {
+		preset := cfg
+		cfg = c.TLSConfig.Clone()
+		if cfg.MinVersion == 0 {
+			cfg.MinVersion = preset.MinVersion
+		}
+		if len(cfg.CipherSuites) == 0 {
+			cfg.CipherSuites = preset.CipherSuites
+		}
+	}
+
+	clientAuth, clientCAs, err := c.tlsClientAuth()
+	if err != nil {
+		return nil, err
+	}
+	if clientAuth != tls.NoClientCert {
+		cfg.ClientAuth = clientAuth
+		cfg.ClientCAs = clientCAs
+	}
+
+	if err := validateTLSConfig(cfg, c.Policy); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// validateTLSConfig - rejects insecure cipher suites and protocol versions not allowed by the policy
+func validateTLSConfig(cfg *tls.Config, policy TLSPolicy) error {
+	if cfg.MinVersion != 0 && cfg.MinVersion < tls.VersionTLS10 {
+		return fmt.Errorf("tls version %s is not supported", tls.VersionName(cfg.MinVersion))
+	}
+	if cfg.MinVersion != 0 && cfg.MinVersion < tls.VersionTLS12 && policy != TLSPolicyLegacy {
+		return fmt.Errorf("tls version %s is allowed only by %s policy", tls.VersionName(cfg.MinVersion), TLSPolicyLegacy)
+	}
+	if cfg.MinVersion < tls.VersionTLS13 && policy == TLSPolicyModern {
+		return fmt.Errorf("tls version %s is not allowed by %s policy", tls.VersionName(max(cfg.MinVersion, tls.VersionTLS12)), TLSPolicyModern)
+	}
+	if cfg.MaxVersion != 0 && cfg.MaxVersion < cfg.MinVersion {
+		return fmt.Errorf("tls max version %s is lower than min version %s", tls.VersionName(cfg.MaxVersion), tls.VersionName(cfg.MinVersion))
+	}
+
+	if cfg.ClientAuth >= tls.VerifyClientCertIfGiven && cfg.ClientCAs == nil {
+		return fmt.Errorf("client certificate verification requires client CAs")
+	}
+
+	secure := map[uint16]bool{}
+	for _, s := range tls.CipherSuites() {
+		secure[s.ID] = true
+	}
+	for _, s := range tls.InsecureCipherSuites() {
+		secure[s.ID] = false
+	}
+	for _, id := range cfg.CipherSuites {
+		ok, known := secure[id]
+		if !known {
+			return fmt.Errorf("unknown tls cipher suite: 0x%04x", id)
+		}
+		if !ok {
+			return fmt.Errorf("insecure tls cipher suite: %s", tls.CipherSuiteName(id))
+		}
+		if !aeadCipherSuite(id) && policy != TLSPolicyLegacy {
+			return fmt.Errorf("tls cipher suite %s is allowed only by %s policy", tls.CipherSuiteName(id), TLSPolicyLegacy)
+		}
+	}
+
+	return nil
+}
+
+// aeadCipherSuite - checks if the cipher suite uses AEAD encryption instead of CBC
+func aeadCipherSuite(id uint16) bool {
+	name := tls.CipherSuiteName(id)
+	return strings.Contains(name, "_GCM_") || strings.Contains(name, "_CHACHA20_POLY1305")
+}
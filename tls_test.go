@@ -0,0 +1,171 @@
+package rest
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTLSPolicy_config(t *testing.T) {
+	testCases := []struct {
+		policy     TLSPolicy
+		minVersion uint16
+	}{
+		{"", tls.VersionTLS12},
+		{TLSPolicyModern, tls.VersionTLS13},
+		{TLSPolicyIntermediate, tls.VersionTLS12},
+		{TLSPolicyLegacy, tls.VersionTLS10},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.policy), func(t *testing.T) {
+			cfg, err := tc.policy.config()
+			require.NoError(t, err)
+			require.Equal(t, tc.minVersion, cfg.MinVersion)
+			require.NoError(t, validateTLSConfig(cfg, tc.policy))
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := TLSPolicy("paranoid").config()
+		require.Error(t, err)
+	})
+}
+
+func TestSSLConfig_tlsConfig(t *testing.T) {
+	t.Run("default policy", func(t *testing.T) {
+		cfg, err := (&SSLConfig{}).tlsConfig()
+		require.NoError(t, err)
+		require.Equal(t, uint16(tls.VersionTLS12), cfg.MinVersion)
+		require.NotContains(t, cfg.CipherSuites, tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA)
+	})
+
+	t.Run("custom config", func(t *testing.T) {
+		custom := &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+		}
+
+		cfg, err := (&SSLConfig{TLSConfig: custom}).tlsConfig()
+		require.NoError(t, err)
+		require.Equal(t, custom.CipherSuites, cfg.CipherSuites)
+		require.False(t, cfg == custom, "custom config must be cloned")
+	})
+
+	t.Run("client auth applied to custom config", func(t *testing.T) {
+		caPath, _ := generateCert(t, t.TempDir(), "ca")
+
+		cfg, err := (&SSLConfig{
+			TLSConfig:    &tls.Config{MinVersion: tls.VersionTLS13},
+			ClientAuth:   ClientAuthRequireAndVerify,
+			ClientCAPath: caPath,
+		}).tlsConfig()
+		require.NoError(t, err)
+		require.Equal(t, tls.RequireAndVerifyClientCert, cfg.ClientAuth)
+		require.NotNil(t, cfg.ClientCAs)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		testCases := []struct {
+			name string
+			ssl  *SSLConfig
+		}{
+			{"unknown policy", &SSLConfig{Policy: "paranoid"}},
+			{"unknown policy with custom config", &SSLConfig{Policy: "paranoid", TLSConfig: &tls.Config{}}},
+			{"old version", &SSLConfig{TLSConfig: &tls.Config{MinVersion: tls.VersionTLS11}}},
+			{"ssl3", &SSLConfig{Policy: TLSPolicyLegacy, TLSConfig: &tls.Config{MinVersion: tls.VersionSSL30}}},
+			{"max lower than min", &SSLConfig{TLSConfig: &tls.Config{MinVersion: tls.VersionTLS13, MaxVersion: tls.VersionTLS12}}},
+			{"insecure cipher suite", &SSLConfig{TLSConfig: &tls.Config{CipherSuites: []uint16{tls.TLS_RSA_WITH_RC4_128_SHA}}}},
+			{"unknown cipher suite", &SSLConfig{TLSConfig: &tls.Config{CipherSuites: []uint16{0xffff}}}},
+			{"verify without client CAs", &SSLConfig{TLSConfig: &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert}}},
+			{"tls 1.2 with modern policy", &SSLConfig{Policy: TLSPolicyModern, TLSConfig: &tls.Config{MinVersion: tls.VersionTLS12}}},
+			{"cbc cipher suite with intermediate policy", &SSLConfig{Policy: TLSPolicyIntermediate, TLSConfig: &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA},
+			}}},
+			{"cbc cipher suite with default policy", &SSLConfig{TLSConfig: &tls.Config{CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA}}}},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				_, err := tc.ssl.tlsConfig()
+				require.Error(t, err)
+			})
+		}
+	})
+
+	t.Run("old version allowed by legacy policy", func(t *testing.T) {
+		_, err := (&SSLConfig{Policy: TLSPolicyLegacy, TLSConfig: &tls.Config{MinVersion: tls.VersionTLS11}}).tlsConfig()
+		require.NoError(t, err)
+	})
+
+	t.Run("cbc cipher suite allowed by legacy policy", func(t *testing.T) {
+		_, err := (&SSLConfig{Policy: TLSPolicyLegacy, TLSConfig: &tls.Config{
+			CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA},
+		}}).tlsConfig()
+		require.NoError(t, err)
+	})
+
+	t.Run("unset fields taken from policy", func(t *testing.T) {
+		cfg, err := (&SSLConfig{Policy: TLSPolicyModern, TLSConfig: &tls.Config{}}).tlsConfig()
+		require.NoError(t, err)
+		require.Equal(t, uint16(tls.VersionTLS13), cfg.MinVersion)
+
+		cfg, err = (&SSLConfig{TLSConfig: &tls.Config{}}).tlsConfig()
+		require.NoError(t, err)
+		require.Equal(t, uint16(tls.VersionTLS12), cfg.MinVersion)
+		require.Equal(t, intermediateCipherSuites, cfg.CipherSuites)
+	})
+}
+
+func TestServer_RunContext_TLSPolicy(t *testing.T) {
+	t.Run("invalid policy fails run", func(t *testing.T) {
+		certPath, keyPath := generateCert(t, t.TempDir(), "server")
+
+		srv := &Server{
+			Port: freePort(t),
+			SSL: &SSLConfig{
+				Port:      freePort(t),
+				CertPath:  certPath,
+				KeyPath:   keyPath,
+				TLSConfig: &tls.Config{MinVersion: tls.VersionTLS10},
+			},
+		}
+		require.Error(t, srv.RunContext(context.Background(), nil))
+	})
+
+	t.Run("modern policy rejects TLS 1.2 clients", func(t *testing.T) {
+		certPath, keyPath := generateCert(t, t.TempDir(), "server")
+
+		srv := &Server{
+			Port: freePort(t),
+			SSL: &SSLConfig{
+				Port:     freePort(t),
+				CertPath: certPath,
+				KeyPath:  keyPath,
+				Policy:   TLSPolicyModern,
+			},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error)
+		go func() {
+			done <- srv.RunContext(ctx, nil)
+		}()
+		addr := fmt.Sprintf("localhost:%d", srv.SSL.Port)
+		waitForServer(t, addr)
+
+		_, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true, MaxVersion: tls.VersionTLS12})
+		require.Error(t, err)
+
+		conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+		require.NoError(t, err)
+		require.Equal(t, uint16(tls.VersionTLS13), conn.ConnectionState().Version)
+		_ = conn.Close()
+
+		cancel()
+		require.NoError(t, <-done)
+	})
+}
@@ -0,0 +1,156 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// SpanContext - trace and span ids of the incoming request propagated by the caller
+type SpanContext struct {
+	TraceID    string
+	SpanID     string // id of the caller span
+	Sampled    bool
+	TraceState string // vendor specific data from the W3C tracestate header
+}
+
+var spanContextKey = &contextKey{"span-context"}
+
+// IsValid - checks if trace and span ids are set
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != "" && sc.SpanID != ""
+}
+
+// ParseSpanContext - reads span context from the W3C traceparent and tracestate headers,
+// B3 single header or B3 multiple headers, in this order
+func ParseSpanContext(h http.Header) (SpanContext, bool) {
+	if sc, ok := parseTraceparent(h.Get("traceparent")); ok {
+		sc.TraceState = strings.Join(h.Values("tracestate"), ",")
+		return sc, true
+	}
+	if sc, ok := parseB3(h.Get("b3")); ok {
+		return sc, true
+	}
+
+	sc := SpanContext{
+		TraceID: strings.ToLower(h.Get("X-B3-TraceId")),
+		SpanID:  strings.ToLower(h.Get("X-B3-SpanId")),
+		Sampled: h.Get("X-B3-Flags") == "1" || h.Get("X-B3-Sampled") == "1" || h.Get("X-B3-Sampled") == "true",
+	}
+	if !validB3ID(sc.TraceID, true) || !validB3ID(sc.SpanID, false) {
+		return SpanContext{}, false
+	}
+	return sc, true
+}
+
+// Tracing - middleware which stores the span context from the request headers in the request context
+func Tracing(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if sc, ok := ParseSpanContext(r.Header); ok {
+			r = r.WithContext(ContextWithSpanContext(r.Context(), sc))
+		}
+		next.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
+
+// ContextWithSpanContext - returns a copy of ctx with the span context
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey, sc)
+}
+
+// GetSpanContext - returns the span context stored by Tracing or ContextWithSpanContext
+func GetSpanContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey).(SpanContext)
+	return sc, ok
+}
+
+// requestSpanContext - returns the span context from the request context or parses it from the headers
+func requestSpanContext(r *http.Request) (SpanContext, bool) {
+	if sc, ok := GetSpanContext(r.Context()); ok {
+		return sc, true
+	}
+	return ParseSpanContext(r.Header)
+}
+
+// traceID - returns trace id of the request: span context, Uber-Trace-Id header or request id
+func traceID(r *http.Request) string {
+	if sc, ok := requestSpanContext(r); ok {
+		return sc.TraceID
+	}
+	if id := r.Header.Get("Uber-Trace-Id"); id != "" {
+		return id
+	}
+	return GetRequestID(r.Context())
+}
+
+// parseTraceparent - parses version-traceid-parentid-flags, future versions may have additional fields
+func parseTraceparent(v string) (SpanContext, bool) {
+	v = strings.TrimSpace(v)
+	if len(v) < 55 || (len(v) > 55 && v[55] != '-') {
+		return SpanContext{}, false
+	}
+	version, traceID, spanID, flags := v[0:2], v[3:35], v[36:52], v[53:55]
+	if v[2] != '-' || v[35] != '-' || v[52] != '-' {
+		return SpanContext{}, false
+	}
+	if !isHex(version) || version == "ff" || (version == "00" && len(v) != 55) {
+		return SpanContext{}, false
+	}
+	if !isHex(traceID) || !isHex(spanID) || !isHex(flags) || isZero(traceID) || isZero(spanID) {
+		return SpanContext{}, false
+	}
+
+	return SpanContext{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: strings.ContainsAny(flags[1:], "13579bdf"),
+	}, true
+}
+
+// parseB3 - parses {TraceId}-{SpanId}-{SamplingState}-{ParentSpanId}, sampling state and parent span are optional
+func parseB3(v string) (SpanContext, bool) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(v)), "-")
+	if len(parts) < 2 || len(parts) > 4 {
+		return SpanContext{}, false
+	}
+	if !validB3ID(parts[0], true) || !validB3ID(parts[1], false) {
+		return SpanContext{}, false
+	}
+	if len(parts) == 4 && !validB3ID(parts[3], false) {
+		return SpanContext{}, false
+	}
+
+	sc := SpanContext{TraceID: parts[0], SpanID: parts[1]}
+	if len(parts) > 2 {
+		switch parts[2] {
+		case "1", "d":
+			sc.Sampled = true
+		case "0":
+		default:
+			return SpanContext{}, false
+		}
+	}
+	return sc, true
+}
+
+// validB3ID - B3 span ids are 16 hex characters, trace ids are 16 or 32
+func validB3ID(id string, trace bool) bool {
+	if len(id) != 16 && (!trace || len(id) != 32) {
+		return false
+	}
+	return isHex(id) && !isZero(id)
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if (s[i] < '0' || s[i] > '9') && (s[i] < 'a' || s[i] > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isZero(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
@@ -0,0 +1,167 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseSpanContext(t *testing.T) {
+	testCases := []struct {
+		name    string
+		headers map[string]string
+		want    SpanContext
+		ok      bool
+	}{
+		{
+			name:    "traceparent",
+			headers: map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "tracestate": "rojo=00f067aa0ba902b7"},
+			want:    SpanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true, TraceState: "rojo=00f067aa0ba902b7"},
+			ok:      true,
+		},
+		{
+			name:    "traceparent not sampled",
+			headers: map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"},
+			want:    SpanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"},
+			ok:      true,
+		},
+		{
+			name:    "traceparent future version",
+			headers: map[string]string{"traceparent": "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+			want:    SpanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true},
+			ok:      true,
+		},
+		{name: "traceparent version ff", headers: map[string]string{"traceparent": "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}},
+		{name: "traceparent zero trace", headers: map[string]string{"traceparent": "00-00000000000000000000000000000000-00f067aa0ba902b7-01"}},
+		{name: "traceparent zero span", headers: map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"}},
+		{name: "traceparent uppercase", headers: map[string]string{"traceparent": "00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-01"}},
+		{name: "traceparent extra fields in version 00", headers: map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"}},
+		{name: "traceparent short", headers: map[string]string{"traceparent": "00-4bf92f3577b34da6-00f067aa0ba902b7-01"}},
+		{
+			name:    "b3 single",
+			headers: map[string]string{"b3": "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1-05e3ac9a4f6e3b90"},
+			want:    SpanContext{TraceID: "80f198ee56343ba864fe8b2a57d3eff7", SpanID: "e457b5a2e4d86bd1", Sampled: true},
+			ok:      true,
+		},
+		{
+			name:    "b3 single 64 bit without sampling",
+			headers: map[string]string{"b3": "a3ce929d0e0e4736-00f067aa0ba902b7"},
+			want:    SpanContext{TraceID: "a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"},
+			ok:      true,
+		},
+		{
+			name:    "b3 single debug",
+			headers: map[string]string{"b3": "a3ce929d0e0e4736-00f067aa0ba902b7-d"},
+			want:    SpanContext{TraceID: "a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true},
+			ok:      true,
+		},
+		{name: "b3 sampling only", headers: map[string]string{"b3": "0"}},
+		{name: "b3 bad sampling", headers: map[string]string{"b3": "a3ce929d0e0e4736-00f067aa0ba902b7-x"}},
+		{
+			name:    "b3 multi",
+			headers: map[string]string{"X-B3-TraceId": "80f198ee56343ba864fe8b2a57d3eff7", "X-B3-SpanId": "e457b5a2e4d86bd1", "X-B3-Sampled": "1"},
+			want:    SpanContext{TraceID: "80f198ee56343ba864fe8b2a57d3eff7", SpanID: "e457b5a2e4d86bd1", Sampled: true},
+			ok:      true,
+		},
+		{name: "b3 multi without span", headers: map[string]string{"X-B3-TraceId": "80f198ee56343ba864fe8b2a57d3eff7"}},
+		{
+			name: "traceparent wins",
+			headers: map[string]string{
+				"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+				"b3":          "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1",
+			},
+			want: SpanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true},
+			ok:   true,
+		},
+		{name: "empty"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := http.Header{}
+			for k, v := range tc.headers {
+				h.Set(k, v)
+			}
+
+			sc, ok := ParseSpanContext(h)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.want, sc)
+			require.Equal(t, tc.ok, sc.IsValid())
+		})
+	}
+}
+
+func TestTracing(t *testing.T) {
+	t.Run("stores span context", func(t *testing.T) {
+		var sc SpanContext
+		var ok bool
+		handler := Tracing(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sc, ok = GetSpanContext(r.Context())
+		}))
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		require.True(t, ok)
+		require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID)
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		require.False(t, ok)
+	})
+
+	t.Run("error response trace id", func(t *testing.T) {
+		testCases := []struct {
+			name    string
+			ctx     context.Context
+			headers map[string]string
+			want    string
+		}{
+			{name: "traceparent", headers: map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "Uber-Trace-Id": "uber"}, want: "4bf92f3577b34da6a3ce929d0e0e4736"},
+			{name: "b3", headers: map[string]string{"b3": "a3ce929d0e0e4736-00f067aa0ba902b7"}, want: "a3ce929d0e0e4736"},
+			{name: "uber", headers: map[string]string{"Uber-Trace-Id": "uber"}, want: "uber"},
+			{name: "context", ctx: ContextWithSpanContext(context.Background(), SpanContext{TraceID: "ctx-trace", SpanID: "ctx-span"}), headers: map[string]string{"Uber-Trace-Id": "uber"}, want: "ctx-trace"},
+			{name: "request id", ctx: context.WithValue(context.Background(), requestIDKey, "req-1"), want: "req-1"},
+			{name: "none"},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				req := httptest.NewRequest("GET", "/", nil)
+				if tc.ctx != nil {
+					req = req.WithContext(tc.ctx)
+				}
+				for k, v := range tc.headers {
+					req.Header.Set(k, v)
+				}
+				w := httptest.NewRecorder()
+				ErrorResponse(w, req, http.StatusBadRequest, nil, "")
+
+				var response HttpError
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				require.Equal(t, tc.want, response.TraceID)
+			})
+		}
+	})
+
+	t.Run("logger", func(t *testing.T) {
+		var buf bytes.Buffer
+		handler := Tracing(LoggerWithConfig(LoggerConfig{Sink: JSONSink(&buf)})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+		require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", record["trace_id"])
+		require.Equal(t, "00f067aa0ba902b7", record["span_id"])
+
+		buf.Reset()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		require.NotContains(t, buf.String(), "trace_id")
+	})
+}
@@ -0,0 +1,409 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// FieldError - failed validation rule of the field
+type FieldError struct {
+	Field   string `json:"field"` // JSON path of the field, like address.zip or items[0].name
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationError - list of the failed validation rules, matches ErrValidate and ErrMissingField if a required field is missing
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// RuleError - invalid rule in the validate tag, a mistake in the struct definition and not in the client input
+type RuleError struct {
+	Field string
+	Rule  string
+	Err   error
+}
+
+var errUnknownRule = errors.New("unknown rule")
+
+var regexCache sync.Map
+
+var validatedTypes sync.Map // reflect.Type -> bool, true if the type can reach a validate tag
+
+// Validate - checks struct fields by the validate tag rules separated by comma:
+// required, omitempty, min=N, max=N, len=N, oneof=a b c, email, regex=pattern. regex must be the last rule of the tag.
+// min, max and len are compared with the value of numbers and with the length of strings, slices and maps.
+// Rules except required are skipped for nil pointers and empty strings, omitempty skips them for any empty value.
+// Unknown rules and rules after dive are skipped, so the tags can be shared with other validators.
+// Returns *ValidationError if any rule fails and *RuleError if a known rule is misused.
+func Validate(v interface{}) error {
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return nil
+		}
+		val = val.Elem()
+	}
+
+	res := &ValidationError{}
+	if err := validateValue(val, func() string { return "" }, res); err != nil {
+		return err
+	}
+	if len(res.Fields) > 0 {
+		return res
+	}
+	return nil
+}
+
+// validateValue - walks structs, slices and maps and checks tagged struct fields.
+// Types without validate tags are skipped, path is built only when a rule fails.
+func validateValue(val reflect.Value, path func() string, res *ValidationError) error {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return nil
+		}
+		val = val.Elem()
+	}
+	if !val.IsValid() || !hasValidateTags(val.Type()) {
+		return nil
+	}
+
+	switch val.Kind() {
+	case reflect.Struct:
+		typ := val.Type()
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			embedded := field.Anonymous && indirectType(field.Type).Kind() == reflect.Struct
+			if !field.IsExported() && !embedded {
+				continue
+			}
+			name, ok := jsonFieldName(field)
+			if !ok {
+				continue
+			}
+
+			fieldPath := func() string { return joinPath(path(), name) }
+			if field.Anonymous && name == "" {
+				fieldPath = path
+			}
+			if tag := field.Tag.Get("validate"); tag != "" {
+				if err := validateField(val.Field(i), fieldPath, tag, res); err != nil {
+					return err
+				}
+			}
+			if err := validateValue(val.Field(i), fieldPath, res); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			if err := validateValue(val.Index(i), func() string { return fmt.Sprintf("%s[%d]", path(), i) }, res); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := val.MapRange()
+		for iter.Next() {
+			key := iter.Key()
+			if err := validateValue(iter.Value(), func() string { return fmt.Sprintf("%s[%v]", path(), key) }, res); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hasValidateTags - checks if values of the type can have fields with validate tags, the result is cached
+func hasValidateTags(t reflect.Type) bool {
+	if ok, found := validatedTypes.Load(t); found {
+		return ok.(bool)
+	}
+	ok := reachValidateTags(t, map[reflect.Type]bool{})
+	validatedTypes.Store(t, ok)
+	return ok
+}
+
+// reachValidateTags - walks the types reachable from t, visited types are skipped to stop on recursive types
+func reachValidateTags(t reflect.Type, visited map[reflect.Type]bool) bool {
+	if visited[t] {
+		return false
+	}
+	visited[t] = true
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return true // the dynamic type is checked on validation
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return reachValidateTags(t.Elem(), visited)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() && !(field.Anonymous && indirectType(field.Type).Kind() == reflect.Struct) {
+				continue
+			}
+			if field.Tag.Get("validate") != "" || reachValidateTags(field.Type, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validateField - checks the value by the rules of the tag
+func validateField(val reflect.Value, path func() string, tag string, res *ValidationError) error {
+	for _, rule := range splitRules(tag) {
+		name, param, _ := strings.Cut(rule, "=")
+
+		switch name {
+		case "required":
+			if isEmpty(val) {
+				res.Fields = append(res.Fields, FieldError{Field: path(), Rule: name, Message: "is required"})
+				return nil
+			}
+			continue
+		case "omitempty":
+			if isEmpty(val) {
+				return nil
+			}
+			continue
+		case "dive":
+			// rules of the elements are not supported
+			return nil
+		}
+
+		v := val
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return nil
+			}
+			v = v.Elem()
+		}
+		if v.Kind() == reflect.String && v.Len() == 0 {
+			return nil
+		}
+
+		msg, err := checkRule(v, name, param)
+		if errors.Is(err, errUnknownRule) {
+			continue
+		}
+		if err != nil {
+			return &RuleError{Field: path(), Rule: rule, Err: err}
+		}
+		if msg != "" {
+			res.Fields = append(res.Fields, FieldError{Field: path(), Rule: name, Param: param, Message: msg})
+		}
+	}
+	return nil
+}
+
+// checkRule - returns message if the value breaks the rule
+func checkRule(v reflect.Value, name, param string) (string, error) {
+	switch name {
+	case "min", "max", "len":
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return "", err
+		}
+		size, unit, err := measure(v)
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case name == "min" && size < limit:
+			return fmt.Sprintf("must be at least %s%s", param, unit), nil
+		case name == "max" && size > limit:
+			return fmt.Sprintf("must be at most %s%s", param, unit), nil
+		case name == "len" && size != limit:
+			return fmt.Sprintf("must be exactly %s%s", param, unit), nil
+		}
+	case "oneof":
+		value, err := valueString(v)
+		if err != nil {
+			return "", err
+		}
+		for _, option := range strings.Fields(param) {
+			if value == option {
+				return "", nil
+			}
+		}
+		return fmt.Sprintf("must be one of: %s", strings.Join(strings.Fields(param), ", ")), nil
+	case "email":
+		if v.Kind() != reflect.String {
+			return "", errors.New("email rule requires a string")
+		}
+		if addr, err := mail.ParseAddress(v.String()); err != nil || addr.Address != v.String() {
+			return "must be a valid email address", nil
+		}
+	case "regex":
+		if v.Kind() != reflect.String {
+			return "", errors.New("regex rule requires a string")
+		}
+		re, err := compileRegex(param)
+		if err != nil {
+			return "", err
+		}
+		if !re.MatchString(v.String()) {
+			return fmt.Sprintf("must match %s", param), nil
+		}
+	default:
+		return "", errUnknownRule
+	}
+	return "", nil
+}
+
+// measure - returns the value of numbers and the length of strings, slices and maps
+func measure(v reflect.Value) (float64, string, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), " characters", nil
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), " items", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), "", nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), "", nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), "", nil
+	}
+	return 0, "", fmt.Errorf("unsupported type %s", v.Type())
+}
+
+// valueString - formats strings, numbers and booleans without Interface, which panics for fields of unexported embedded structs
+func valueString(v reflect.Value) (string, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", v.Type())
+}
+
+// splitRules - splits the tag by comma, the regex rule takes the rest of the tag
+func splitRules(tag string) []string {
+	var rules []string
+	for tag != "" {
+		if strings.HasPrefix(tag, "regex=") {
+			return append(rules, tag)
+		}
+		rule, rest, _ := strings.Cut(tag, ",")
+		if rule = strings.TrimSpace(rule); rule != "" {
+			rules = append(rules, rule)
+		}
+		tag = strings.TrimSpace(rest)
+	}
+	return rules
+}
+
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexCache.Store(pattern, re)
+	return re, nil
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+// isEmpty - checks if the value is missing: nil, zero or empty collection
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return v.IsZero()
+}
+
+// jsonFieldName - returns the field name used in JSON, false for skipped fields
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" && !field.Anonymous {
+		name = field.Name
+	}
+	return name, true
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		fields = append(fields, f.Field+" "+f.Message)
+	}
+	return "validation failed: " + strings.Join(fields, "; ")
+}
+
+// Is - matches ErrValidate, and ErrMissingField if a required field is missing
+func (e *ValidationError) Is(target error) bool {
+	if target == ErrValidate {
+		return true
+	}
+	if target == ErrMissingField {
+		for _, f := range e.Fields {
+			if f.Rule == "required" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e *ValidationError) StatusCode() int {
+	return http.StatusUnprocessableEntity
+}
+
+func (e *ValidationError) PublicCode() string {
+	return ErrValidate.Error()
+}
+
+func (e *ValidationError) PublicMessage() string {
+	return e.Error()
+}
+
+func (e *RuleError) Error() string {
+	return fmt.Sprintf("invalid validation rule %q of field %s, %s", e.Rule, e.Field, e.Err)
+}
+
+func (e *RuleError) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode - rule errors are server errors, the client can't fix them
+func (e *RuleError) StatusCode() int {
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,316 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type validateAddress struct {
+	City string `json:"city" validate:"required"`
+	Zip  string `json:"zip" validate:"len=5,regex=^[0-9]+$"`
+}
+
+type validateItem struct {
+	Name string `json:"name" validate:"required,max=5"`
+}
+
+type validateBase struct {
+	ID   int    `json:"id" validate:"min=1"`
+	Kind string `json:"kind" validate:"oneof=person company"`
+}
+
+type validateUser struct {
+	validateBase
+	Name     string            `json:"name" validate:"required,min=2,max=10"`
+	Email    string            `json:"email" validate:"email"`
+	Role     string            `json:"role" validate:"oneof=admin user"`
+	Age      *int              `json:"age" validate:"min=18,max=150"`
+	Tags     []string          `json:"tags" validate:"max=2"`
+	Address  *validateAddress  `json:"address" validate:"required"`
+	Items    []validateItem    `json:"items"`
+	Contacts map[string]string `json:"contacts"`
+	Ignored  string            `json:"-" validate:"required"`
+	Untagged string            `validate:"max=3"`
+}
+
+func validUser() validateUser {
+	age := 30
+	return validateUser{
+		validateBase: validateBase{ID: 1},
+		Name:         "John",
+		Email:        "john@example.com",
+		Role:         "admin",
+		Age:          &age,
+		Tags:         []string{"a"},
+		Address:      &validateAddress{City: "Berlin", Zip: "10115"},
+		Items:        []validateItem{{Name: "book"}},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		u := validUser()
+		require.NoError(t, Validate(u))
+		require.NoError(t, Validate(&u))
+		require.NoError(t, Validate(nil))
+		require.NoError(t, Validate((*validateUser)(nil)))
+	})
+
+	t.Run("optional empty values", func(t *testing.T) {
+		u := validUser()
+		u.Email, u.Role, u.Age = "", "", nil
+		u.Address.Zip = ""
+		require.NoError(t, Validate(u))
+	})
+
+	testCases := []struct {
+		name   string
+		modify func(u *validateUser)
+		want   []FieldError
+	}{
+		{
+			name:   "required",
+			modify: func(u *validateUser) { u.Name = ""; u.Address = nil },
+			want: []FieldError{
+				{Field: "name", Rule: "required", Message: "is required"},
+				{Field: "address", Rule: "required", Message: "is required"},
+			},
+		},
+		{
+			name:   "min and max length",
+			modify: func(u *validateUser) { u.Name = "J"; u.Tags = []string{"a", "b", "c"}; u.Untagged = "long" },
+			want: []FieldError{
+				{Field: "name", Rule: "min", Param: "2", Message: "must be at least 2 characters"},
+				{Field: "tags", Rule: "max", Param: "2", Message: "must be at most 2 items"},
+				{Field: "Untagged", Rule: "max", Param: "3", Message: "must be at most 3 characters"},
+			},
+		},
+		{
+			name:   "unicode length",
+			modify: func(u *validateUser) { u.Name = "Юлия" },
+		},
+		{
+			name: "number",
+			modify: func(u *validateUser) {
+				age := 10
+				u.Age = &age
+				u.ID = 0
+			},
+			want: []FieldError{
+				{Field: "id", Rule: "min", Param: "1", Message: "must be at least 1"},
+				{Field: "age", Rule: "min", Param: "18", Message: "must be at least 18"},
+			},
+		},
+		{
+			name:   "embedded oneof",
+			modify: func(u *validateUser) { u.Kind = "robot" },
+			want:   []FieldError{{Field: "kind", Rule: "oneof", Param: "person company", Message: "must be one of: person, company"}},
+		},
+		{
+			name:   "email",
+			modify: func(u *validateUser) { u.Email = "John <john@example.com>" },
+			want:   []FieldError{{Field: "email", Rule: "email", Message: "must be a valid email address"}},
+		},
+		{
+			name:   "oneof",
+			modify: func(u *validateUser) { u.Role = "root" },
+			want:   []FieldError{{Field: "role", Rule: "oneof", Param: "admin user", Message: "must be one of: admin, user"}},
+		},
+		{
+			name:   "nested",
+			modify: func(u *validateUser) { u.Address.City = ""; u.Address.Zip = "1234a" },
+			want: []FieldError{
+				{Field: "address.city", Rule: "required", Message: "is required"},
+				{Field: "address.zip", Rule: "regex", Param: "^[0-9]+$", Message: "must match ^[0-9]+$"},
+			},
+		},
+		{
+			name:   "len",
+			modify: func(u *validateUser) { u.Address.Zip = "123" },
+			want:   []FieldError{{Field: "address.zip", Rule: "len", Param: "5", Message: "must be exactly 5 characters"}},
+		},
+		{
+			name:   "slice elements",
+			modify: func(u *validateUser) { u.Items = append(u.Items, validateItem{}, validateItem{Name: "toolong"}) },
+			want: []FieldError{
+				{Field: "items[1].name", Rule: "required", Message: "is required"},
+				{Field: "items[2].name", Rule: "max", Param: "5", Message: "must be at most 5 characters"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u := validUser()
+			tc.modify(&u)
+
+			err := Validate(&u)
+			if tc.want == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			var validationErr *ValidationError
+			require.True(t, errors.As(err, &validationErr))
+			require.Equal(t, tc.want, validationErr.Fields)
+			require.True(t, errors.Is(err, ErrValidate))
+		})
+	}
+
+	t.Run("missing field", func(t *testing.T) {
+		u := validUser()
+		u.Name = ""
+		require.True(t, errors.Is(Validate(u), ErrMissingField))
+
+		u = validUser()
+		u.Role = "root"
+		require.False(t, errors.Is(Validate(u), ErrMissingField))
+	})
+
+	t.Run("error text", func(t *testing.T) {
+		u := validUser()
+		u.Name, u.Role = "", "root"
+		require.Equal(t, "validation failed: name is required; role must be one of: admin, user", Validate(u).Error())
+	})
+
+	t.Run("invalid rule", func(t *testing.T) {
+		err := Validate(struct {
+			Name string `validate:"min=abc"`
+		}{Name: "John"})
+		require.EqualError(t, err, `invalid validation rule "min=abc" of field Name, strconv.ParseFloat: parsing "abc": invalid syntax`)
+		var ruleErr *RuleError
+		require.True(t, errors.As(err, &ruleErr))
+		mapping, _ := DefaultErrorRegistry.resolve(err, http.StatusBadRequest)
+		require.Equal(t, http.StatusInternalServerError, mapping.Status)
+		var validationErr *ValidationError
+		require.False(t, errors.As(err, &validationErr))
+	})
+
+	t.Run("unknown rules skipped", func(t *testing.T) {
+		type item struct {
+			Count int      `validate:"omitempty,gte=2,min=2"`
+			Tags  []string `validate:"dive,required"`
+			Name  string   `validate:"unknown,max=3"`
+		}
+		require.NoError(t, Validate(item{Tags: []string{""}}))
+		require.NoError(t, Validate(item{Count: 2, Name: "abc"}))
+		require.EqualError(t, Validate(item{Count: 1, Name: "abcd"}), "validation failed: Count must be at least 2; Name must be at most 3 characters")
+	})
+
+	t.Run("regex with comma", func(t *testing.T) {
+		type code struct {
+			Code string `validate:"required,regex=^[a-z]{2,3}$"`
+		}
+		require.NoError(t, Validate(code{Code: "abc"}))
+		require.Error(t, Validate(code{Code: "abcd"}))
+	})
+}
+
+type validateNode struct {
+	Children []*validateNode `json:"children"`
+	Leaf     *validateItem   `json:"leaf"`
+}
+
+func TestHasValidateTags(t *testing.T) {
+	type plain struct {
+		Data   []byte
+		Values []int
+		Index  map[string][]float64
+	}
+	type hidden struct {
+		item validateItem
+	}
+
+	testCases := []struct {
+		name string
+		typ  reflect.Type
+		want bool
+	}{
+		{name: "scalar", typ: reflect.TypeOf(0), want: false},
+		{name: "struct without tags", typ: reflect.TypeOf(plain{}), want: false},
+		{name: "unexported field", typ: reflect.TypeOf(hidden{}), want: false},
+		{name: "tagged struct", typ: reflect.TypeOf(validateItem{}), want: true},
+		{name: "slice of tagged structs", typ: reflect.TypeOf([]*validateItem{}), want: true},
+		{name: "map of tagged structs", typ: reflect.TypeOf(map[string]validateItem{}), want: true},
+		{name: "recursive", typ: reflect.TypeOf(validateNode{}), want: true},
+		{name: "interface", typ: reflect.TypeOf([]interface{}{}), want: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, hasValidateTags(tc.typ))
+		})
+	}
+
+	t.Run("recursive element path", func(t *testing.T) {
+		node := validateNode{Children: []*validateNode{{}, {Leaf: &validateItem{}}}}
+		var validationErr *ValidationError
+		require.True(t, errors.As(Validate(node), &validationErr))
+		require.Equal(t, []FieldError{{Field: "children[1].leaf.name", Rule: "required", Message: "is required"}}, validationErr.Fields)
+	})
+
+	t.Run("interface elements", func(t *testing.T) {
+		require.NoError(t, Validate([]interface{}{1, "a", validateItem{Name: "book"}}))
+		require.Error(t, Validate([]interface{}{1, validateItem{}}))
+	})
+}
+
+func TestValidate_ReadBody(t *testing.T) {
+	t.Run("read body", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(`{"id":1,"name":"J","address":{"city":"Berlin"}}`))
+		var u validateUser
+		err := ReadBody(req, &u)
+		require.True(t, errors.Is(err, ErrValidate))
+		require.Equal(t, "J", u.Name)
+	})
+
+	t.Run("decode", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(`{"id":1,"name":"John"}`))
+		_, err := Decode[validateUser](req)
+		require.True(t, errors.Is(err, ErrMissingField))
+		require.False(t, errors.Is(err, ErrUnmarshal))
+	})
+
+	t.Run("tags of other validators", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(`{"age":0}`))
+		var v struct {
+			Age int `json:"age" validate:"omitempty,gte=2"`
+		}
+		require.NoError(t, ReadBody(req, &v))
+	})
+
+	t.Run("invalid rule is not a client error", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(`{"age":1}`))
+		w := httptest.NewRecorder()
+		var v struct {
+			Age int `json:"age" validate:"min=x"`
+		}
+		ErrorResponse(w, req, http.StatusBadRequest, ReadBody(req, &v), "")
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("error response", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(`{"id":1,"name":"J","role":"root","address":{"city":""}}`))
+		w := httptest.NewRecorder()
+
+		var u validateUser
+		err := ReadBody(req, &u)
+		ErrorResponse(w, req, http.StatusBadRequest, err, "")
+
+		require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Equal(t, "VALIDATION_ERROR", response["error"])
+		require.Equal(t, []interface{}{
+			map[string]interface{}{"field": "name", "rule": "min", "param": "2", "message": "must be at least 2 characters"},
+			map[string]interface{}{"field": "role", "rule": "oneof", "param": "admin user", "message": "must be one of: admin, user"},
+			map[string]interface{}{"field": "address.city", "rule": "required", "message": "is required"},
+		}, response["details"])
+	})
+}